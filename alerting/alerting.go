@@ -3,70 +3,377 @@ package alerting
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/ayoubed/datadog-home-project/database"
+	"github.com/ayoubed/datadog-home-project/eventlog"
+	"github.com/ayoubed/datadog-home-project/maintenance"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/request"
 	"github.com/ayoubed/datadog-home-project/statsagent"
 	"github.com/fatih/color"
 )
 
 var red *color.Color = color.New(color.FgRed)
 var green *color.Color = color.New(color.FgGreen)
+var yellow *color.Color = color.New(color.FgYellow)
 
-// websiteUp is a map that keeps track of the state of each website we're monitoring
-var websiteUp map[string]bool = make(map[string]bool)
+// Alert severities, used for routing and display
+const (
+	SeverityCritical string = "critical"
+	SeverityWarning  string = "warning"
+	SeverityInfo     string = "info"
+)
 
 // AlertConfig represents all the useful info for our alert logic
+// AvailabilityThreshold triggers the down alerts, RecoveryThreshold the up alerts (defaults to AvailabilityThreshold)
+// A website must meet the condition for MinStateDuration seconds before changing state
+// A website changing state FlapThreshold times within FlapWindow seconds is flapping, its alerts are suppressed
 type AlertConfig struct {
-	AvailabilityInterval  int64   `json:"availabilityInterval"`
-	AvailabilityThreshold float64 `json:"availabilityThreshold"`
-	CheckInterval         int     `json:"checkInterval"`
+	AvailabilityInterval  int64            `json:"availabilityInterval"`
+	AvailabilityThreshold float64          `json:"availabilityThreshold"`
+	RecoveryThreshold     float64          `json:"recoveryThreshold"`
+	MinStateDuration      int64            `json:"minStateDuration"`
+	FlapWindow            int64            `json:"flapWindow"`
+	FlapThreshold         int              `json:"flapThreshold"`
+	CheckInterval         int              `json:"checkInterval"`
+	Notifiers             []NotifierConfig `json:"notifiers"`
+	Routes                []Route          `json:"routes"`
+}
+
+// Alert is a change in the state of a website detected by the alert logic
+type Alert struct {
+	URL          string    `json:"url"`
+	Up           bool      `json:"up"`
+	Availability float64   `json:"availability"`
+	Time         time.Time `json:"time"`
+	Severity     string    `json:"severity"`
+	Message      string    `json:"message"`
+	// Silenced is set for alerts raised during a silence or a maintenance window, they are not notified
+	Silenced bool `json:"silenced"`
+	// SuppressedBy is the down parent of the website, the alert is not notified
+	SuppressedBy string `json:"suppressedBy,omitempty"`
+	// Affected lists the down websites depending on the website of a root-cause alert
+	Affected []string `json:"affected,omitempty"`
+	// Tags and Group are the ones of the website, they can be used to route the alert
+	Tags  map[string]string `json:"tags,omitempty"`
+	Group string            `json:"group,omitempty"`
+}
+
+// Reload is a new configuration for the alert logic
+type Reload struct {
+	Websites []monitor.Website
+	Config   AlertConfig
+}
+
+// rules is everything the alert logic derives from its configuration
+type rules struct {
+	config         AlertConfig
+	urls           []string
+	checkIntervals map[string]int64
+	websites       map[string]monitor.Website
+	deps           dependencies
+	router         *router
+}
+
+// newRules builds the rules of the alert logic
+// the pending alerts and incidents of the routes of the previous router that are left unchanged are kept
+func newRules(websites []monitor.Website, alertConfig AlertConfig, previous *router) (*rules, error) {
+	if alertConfig.CheckInterval <= 0 {
+		return nil, fmt.Errorf("alerting should have a positive checkInterval")
+	}
+	if alertConfig.AvailabilityInterval <= 0 {
+		return nil, fmt.Errorf("alerting should have a positive availabilityInterval")
+	}
+	if alertConfig.AvailabilityThreshold < 0 || alertConfig.AvailabilityThreshold > 1 || alertConfig.RecoveryThreshold < 0 || alertConfig.RecoveryThreshold > 1 {
+		return nil, fmt.Errorf("alerting thresholds should be between 0 and 1")
+	}
+	if alertConfig.RecoveryThreshold != 0 && alertConfig.RecoveryThreshold < alertConfig.AvailabilityThreshold {
+		return nil, fmt.Errorf("alerting recoveryThreshold should not be lower than availabilityThreshold")
+	}
+	if alertConfig.MinStateDuration < 0 || alertConfig.FlapWindow < 0 || alertConfig.FlapThreshold < 0 {
+		return nil, fmt.Errorf("alerting minStateDuration, flapWindow and flapThreshold should not be negative")
+	}
+
+	r := &rules{config: alertConfig, checkIntervals: make(map[string]int64), websites: make(map[string]monitor.Website)}
+	for _, ws := range websites {
+		if ws.Paused {
+			continue
+		}
+		r.urls = append(r.urls, ws.URL)
+		r.checkIntervals[ws.URL] = int64(ws.CheckInterval)
+		r.websites[ws.URL] = ws
+	}
+
+	deps, err := newDependencies(websites)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the website dependencies: %v", err)
+	}
+	r.deps = deps
+
+	notifiers, err := newNotifiers(alertConfig.Notifiers)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the alert notifiers: %v", err)
+	}
+	router, err := newRouter(alertConfig.Routes, notifiers)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up the alert routes: %v", err)
+	}
+	router.inherit(previous)
+	r.router = router
+
+	return r, nil
+}
+
+// restore sets the state of the new websites from their last stored alert,
+// and reopens the incidents of the ones that were down, so they are still repeated, escalated and can be acknowledged
+func (r *rules) restore(now time.Time) error {
+	down, err := restoreState(r.urls)
+	if err != nil {
+		return fmt.Errorf("error restoring the alert state: %v", err)
+	}
+	for _, alert := range down {
+		alert.Tags = r.websites[alert.URL].Tags
+		alert.Group = r.websites[alert.URL].Group
+		r.router.reopen(now, alert)
+	}
+	return nil
+}
+
+// Validate checks that the alert config can be applied to the given websites
+func Validate(websites []monitor.Website, alertConfig AlertConfig) error {
+	_, err := newRules(websites, alertConfig, nil)
+	return err
 }
 
 // Run monitors the availability of websites
 // It send an alert to the dashboard, if the availability of some website over a given interval
 // is bellow the given the threshold
-func Run(ctx context.Context, alertc chan string, websitesMap map[string]int64, alertConfig AlertConfig) error {
-	urls := make([]string, 0)
-	for k := range websitesMap {
-		websiteUp[k] = true
-		urls = append(urls, k)
+// Alerts are also handed to the router, that forwards them to the notifiers matching the configured routes,
+// the notifications are sent by their own goroutine
+// The websites and the config can be replaced through reloadc, the state of the websites is kept
+func Run(ctx context.Context, alertc chan string, websites []monitor.Website, alertConfig AlertConfig, reloadc <-chan Reload) error {
+	r, err := newRules(websites, alertConfig, nil)
+	if err != nil {
+		return err
+	}
+	if err := r.restore(time.Now()); err != nil {
+		return err
 	}
-	ticker := time.NewTicker(time.Duration(alertConfig.CheckInterval) * time.Second)
+	setRouter(r.router)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	deliveries := make(chan delivery, deliveryQueueSize)
+	go deliver(ctx, deliveries, alertc)
+
+	ticker := time.NewTicker(time.Duration(r.config.CheckInterval) * time.Second)
+	flushTicker := time.NewTicker(time.Second)
 
 	for {
 		select {
 		case <-ctx.Done():
 			ticker.Stop()
+			flushTicker.Stop()
 			return nil
+		case reload := <-reloadc:
+			newR, err := newRules(reload.Websites, reload.Config, r.router)
+			if err != nil {
+				report(ctx, alertc, red.Sprintf("Alert config reload failed, keeping the previous one: %v\n", err))
+				continue
+			}
+			if err := newR.restore(time.Now()); err != nil {
+				return err
+			}
+			if newR.config.CheckInterval != r.config.CheckInterval {
+				ticker.Stop()
+				ticker = time.NewTicker(time.Duration(newR.config.CheckInterval) * time.Second)
+			}
+			r = newR
+			setRouter(r.router)
 		case t := <-ticker.C:
-			for _, url := range urls {
-				v, err := statsagent.GetAvailabilityForTimeFrame(url, t, alertConfig.AvailabilityInterval)
+			alerts := make([]Alert, 0)
+			for _, url := range r.urls {
+				v, err := statsagent.GetAvailabilityForTimeFrame(url, t, r.config.AvailabilityInterval)
 				if err != nil {
 					return fmt.Errorf("error while executing the alert process: %v", err)
 				}
 
-				result := getAlertMessage(t, url, websiteUp[url], websitesMap[url], v, alertConfig)
-				if result != "" {
-					alertc <- result
+				statesMu.Lock()
+				alert, ok := getAlert(t, url, states[url], r.checkIntervals[url], v, r.config)
+				statesMu.Unlock()
+				if ok {
+					alerts = append(alerts, alert)
+				}
+			}
+
+			// dependencies are applied once every website is evaluated, so parents are known to be down
+			statesMu.Lock()
+			alerts = r.deps.apply(t, alerts)
+			statesMu.Unlock()
+
+			for _, alert := range alerts {
+				alert.Silenced = maintenance.Active(alert.URL, t)
+				alert.Tags = r.websites[alert.URL].Tags
+				alert.Group = r.websites[alert.URL].Group
+				// an alert that can't be stored is still shown and notified, it is only missing from the history
+				if err := database.WriteAlertEvent(toEvent(alert)); err != nil {
+					eventlog.Error(fmt.Errorf("error while storing the alert of %v: %v", alert.URL, err))
+				}
+				publish(alert)
+				if alert.SuppressedBy == "" {
+					r.router.dispatch(t, alert)
+				}
+			}
+		case t := <-flushTicker.C:
+			for _, d := range r.router.flush(t) {
+				select {
+				case deliveries <- d:
+				default:
+					report(ctx, alertc, red.Sprintf("Alert notification to %v dropped, too many notifications are waiting, time = %s\n", d.name, t.Format(time.RFC1123)))
 				}
 			}
 		}
 	}
 }
 
-func getAlertMessage(t time.Time, url string, up bool, websiteCheckInterval int64, v statsagent.AvailabilityRange, alertConfig AlertConfig) string {
-	var alertMessage string = ""
+func getAlert(t time.Time, url string, state *siteState, websiteCheckInterval int64, v statsagent.AvailabilityRange, alertConfig AlertConfig) (Alert, bool) {
 	var tm int64 = (v.Start.Unix() - (t.Unix() - alertConfig.AvailabilityInterval))
+	state.availability = v.Availability
+	recoveryThreshold := alertConfig.RecoveryThreshold
+	if recoveryThreshold == 0 {
+		recoveryThreshold = alertConfig.AvailabilityThreshold
+	}
 
-	if tm >= 0 && tm <= websiteCheckInterval && (v.Availability <= alertConfig.AvailabilityThreshold && up == true) || (v.Availability > alertConfig.AvailabilityThreshold && up == false) {
+	if tm >= 0 && tm <= websiteCheckInterval && (v.Availability <= alertConfig.AvailabilityThreshold && state.up == true) || (v.Availability > recoveryThreshold && state.up == false) {
+		// the website has to stay in the new state long enough before we switch
+		if state.pendingSince.IsZero() {
+			state.pendingSince = t
+		}
+		if t.Sub(state.pendingSince) < time.Duration(alertConfig.MinStateDuration)*time.Second {
+			return Alert{}, false
+		}
+		state.pendingSince = time.Time{}
 
-		if v.Availability > alertConfig.AvailabilityThreshold {
-			alertMessage = green.Sprintf("Website %v is up. availability = %.2f%%, time = %s\n", url, 100*v.Availability, t.Format(time.RFC1123))
+		alert := Alert{URL: url, Availability: v.Availability, Time: t}
+		if v.Availability > recoveryThreshold {
+			alert.Up = true
+			alert.Severity = SeverityInfo
+			alert.Message = fmt.Sprintf("Website %v is up. availability = %.2f%%, time = %s\n", url, 100*v.Availability, t.Format(time.RFC1123))
 		} else {
-			alertMessage = red.Sprintf("Website %v is down. availability = %.2f%%, time = %s\n", url, 100*v.Availability, t.Format(time.RFC1123))
+			alert.Severity = SeverityCritical
+			alert.Message = fmt.Sprintf("Website %v is down. availability = %.2f%%, time = %s\n", url, 100*v.Availability, t.Format(time.RFC1123))
+		}
+
+		state.up = alert.Up
+		wasFlapping := state.flapping
+		state.recordTransition(t, alertConfig)
+		if state.flapping {
+			if wasFlapping {
+				return Alert{}, false
+			}
+			alert.Severity = SeverityWarning
+			alert.Message = fmt.Sprintf("Website %v is flapping, alerts are suppressed. availability = %.2f%%, time = %s\n", url, 100*v.Availability, t.Format(time.RFC1123))
+		}
+		return alert, true
+	}
+
+	state.pendingSince = time.Time{}
+	if state.flapping && state.pruneTransitions(t, alertConfig) {
+		alert := Alert{URL: url, Up: state.up, Availability: v.Availability, Time: t, Severity: SeverityInfo}
+		if !state.up {
+			alert.Severity = SeverityCritical
 		}
+		alert.Message = fmt.Sprintf("Website %v stopped flapping and is %v. availability = %.2f%%, time = %s\n", url, state.status(), 100*v.Availability, t.Format(time.RFC1123))
+		return alert, true
+	}
+	return Alert{}, false
+}
+
+// Evaluate applies the alert rules once to a batch of checks of a website, as if it was up before them
+// there is no hysteresis nor flap detection, the alert is down when the availability is at or under the threshold
+func Evaluate(t time.Time, url string, records []request.ResponseLog, alertConfig AlertConfig) Alert {
+	v := statsagent.GetAvailabilityForRecords(records, t)
+	alert := Alert{URL: url, Up: true, Availability: v.Availability, Time: t, Severity: SeverityInfo}
+	if v.Availability <= alertConfig.AvailabilityThreshold {
+		alert.Up = false
+		alert.Severity = SeverityCritical
+		alert.Message = fmt.Sprintf("Website %v is down. availability = %.2f%%, time = %s\n", url, 100*v.Availability, t.Format(time.RFC1123))
+	} else {
+		alert.Message = fmt.Sprintf("Website %v is up. availability = %.2f%%, time = %s\n", url, 100*v.Availability, t.Format(time.RFC1123))
+	}
+	return alert
+}
 
-		websiteUp[url] = v.Availability > alertConfig.AvailabilityThreshold
+// Same tells if two alerts are the same event of a website, stored alerts having their time rounded down to the millisecond
+func (a Alert) Same(b Alert) bool {
+	return a.URL == b.URL && a.Up == b.Up && a.Time.Truncate(time.Millisecond).Equal(b.Time.Truncate(time.Millisecond))
+}
+
+// History returns the last stored alerts, oldest first
+func History(limit int) ([]Alert, error) {
+	events, err := database.GetAlertEvents(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]Alert, 0, len(events))
+	for _, event := range events {
+		alerts = append(alerts, fromEvent(event))
+	}
+	return alerts, nil
+}
+
+// HistorySince returns the stored alerts of a time range, oldest first, preceded by the last down alert
+// of each of the given websites that was down at its start, so the incidents ongoing at the start are complete
+func HistorySince(urls []string, since time.Time) ([]Alert, error) {
+	alerts := make([]Alert, 0)
+	for _, url := range urls {
+		event, ok, err := database.GetLastAlertEvent(url, since)
+		if err != nil {
+			return nil, err
+		}
+		if ok && !event.Up {
+			alerts = append(alerts, fromEvent(event))
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Time.Before(alerts[j].Time) })
+
+	events, err := database.GetAlertEventsSince(since)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		alerts = append(alerts, fromEvent(event))
+	}
+	return alerts, nil
+}
+
+func fromEvent(event database.AlertEvent) Alert {
+	return Alert{URL: event.URL, Up: event.Up, Availability: event.Availability, Time: event.Timestamp, Severity: event.Severity, Message: event.Message,
+		SuppressedBy: event.SuppressedBy, Silenced: event.Silenced}
+}
+
+func toEvent(alert Alert) database.AlertEvent {
+	return database.AlertEvent{Timestamp: alert.Time, URL: alert.URL, Up: alert.Up, Availability: alert.Availability, Severity: alert.Severity, Message: alert.Message,
+		SuppressedBy: alert.SuppressedBy, Silenced: alert.Silenced}
+}
+
+// Format colors an alert message for the terminal
+func Format(alert Alert) string {
+	message := alert.Message
+	if alert.SuppressedBy != "" {
+		message = fmt.Sprintf("[suppressed, %v is down] %v", alert.SuppressedBy, message)
+	}
+	if alert.Silenced {
+		message = "[silenced] " + message
+	}
+	if alert.Severity == SeverityWarning {
+		return yellow.Sprint(message)
+	}
+	if alert.Up {
+		return green.Sprint(message)
 	}
-	return alertMessage
+	return red.Sprint(message)
 }
@@ -53,13 +53,13 @@ func TestAlertLogic(t *testing.T) {
 			"https://google.com",
 			start,
 			[]request.ResponseLog{
-				{start.Add(-time.Duration(7) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(6) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(5) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(4) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(3) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(2) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(1) * time.Second), "200", "https://google.com", 0, 0, true},
+				{Timestamp: start.Add(-time.Duration(7) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(6) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(5) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(4) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(3) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(2) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(1) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
 			},
 			true,
 			"",
@@ -69,15 +69,15 @@ func TestAlertLogic(t *testing.T) {
 			"https://google.com",
 			start,
 			[]request.ResponseLog{
-				{start.Add(-time.Duration(10) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(8) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(7) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(6) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(5) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(4) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(3) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(2) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(1) * time.Second), "200", "https://google.com", 0, 0, true},
+				{Timestamp: start.Add(-time.Duration(10) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(8) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(7) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(6) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(5) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(4) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(3) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(2) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(1) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
 			},
 			true,
 			"",
@@ -87,15 +87,15 @@ func TestAlertLogic(t *testing.T) {
 			"https://google.com",
 			start,
 			[]request.ResponseLog{
-				{start.Add(-time.Duration(10) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(8) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(7) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(6) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(5) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(4) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(3) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(2) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(1) * time.Second), "200", "https://google.com", 0, 0, true},
+				{Timestamp: start.Add(-time.Duration(10) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(8) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(7) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(6) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(5) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(4) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(3) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(2) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(1) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
 			},
 			true,
 			fmt.Sprintf("Website https://google.com is down. availability = 66.67%%, time = %s\n", start.Format(time.RFC1123)),
@@ -105,15 +105,15 @@ func TestAlertLogic(t *testing.T) {
 			"https://google.com",
 			start,
 			[]request.ResponseLog{
-				{start.Add(-time.Duration(10) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(8) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(7) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(6) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(5) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(4) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(3) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(2) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(1) * time.Second), "200", "https://google.com", 0, 0, true},
+				{Timestamp: start.Add(-time.Duration(10) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(8) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(7) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(6) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(5) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(4) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(3) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(2) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(1) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
 			},
 			false,
 			"",
@@ -123,15 +123,15 @@ func TestAlertLogic(t *testing.T) {
 			"https://google.com",
 			start,
 			[]request.ResponseLog{
-				{start.Add(-time.Duration(10) * time.Second), "200", "https://google.com", 0, 0, false},
-				{start.Add(-time.Duration(8) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(7) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(6) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(5) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(4) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(3) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(2) * time.Second), "200", "https://google.com", 0, 0, true},
-				{start.Add(-time.Duration(1) * time.Second), "200", "https://google.com", 0, 0, true},
+				{Timestamp: start.Add(-time.Duration(10) * time.Second), StatusCode: "200", URL: "https://google.com", Success: false},
+				{Timestamp: start.Add(-time.Duration(8) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(7) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(6) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(5) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(4) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(3) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(2) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
+				{Timestamp: start.Add(-time.Duration(1) * time.Second), StatusCode: "200", URL: "https://google.com", Success: true},
 			},
 			false,
 			fmt.Sprintf("Website https://google.com is up. availability = 88.89%%, time = %s\n", start.Format(time.RFC1123)),
@@ -141,11 +141,70 @@ func TestAlertLogic(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			availability := statsagent.GetAvailabilityForRecords(tt.records, tt.origin)
-			alertMessage := getAlertMessage(tt.origin, tt.URL, tt.websitestateUp, 1, availability, alertConfig)
+			alert, _ := getAlert(tt.origin, tt.URL, &siteState{up: tt.websitestateUp}, 1, availability, alertConfig)
 
-			if !reflect.DeepEqual(alertMessage, tt.expectedAlertMessage) {
-				t.Errorf("Got %v, want %v", alertMessage, tt.expectedAlertMessage)
+			if !reflect.DeepEqual(alert.Message, tt.expectedAlertMessage) {
+				t.Errorf("Got %v, want %v", alert.Message, tt.expectedAlertMessage)
 			}
 		})
 	}
 }
+
+func TestHysteresisAndFlapping(t *testing.T) {
+	config := AlertConfig{AvailabilityInterval: 10, AvailabilityThreshold: 0.8, RecoveryThreshold: 0.9, MinStateDuration: 2, FlapWindow: 60, FlapThreshold: 3}
+	start := time.Now()
+	state := &siteState{up: true}
+	// the first record is at the start of the timeframe, so we have enough records
+	availability := func(t time.Time, v float64) statsagent.AvailabilityRange {
+		return statsagent.AvailabilityRange{Availability: v, Start: t.Add(-10 * time.Second)}
+	}
+
+	steps := []struct {
+		name             string
+		offset           time.Duration
+		availability     float64
+		expectedSeverity string
+		expectedStatus   string
+	}{
+		{"below the threshold, waiting for the minimum state duration", 0, 0.5, "", StatusUp},
+		{"still below the threshold, goes down", 2 * time.Second, 0.5, SeverityCritical, StatusDown},
+		{"above the trigger threshold but below the recovery threshold", 4 * time.Second, 0.85, "", StatusDown},
+		{"above the recovery threshold, waiting for the minimum state duration", 6 * time.Second, 0.95, "", StatusDown},
+		{"still above the recovery threshold, goes up", 8 * time.Second, 0.95, SeverityInfo, StatusUp},
+		{"below the threshold, waiting for the minimum state duration", 10 * time.Second, 0.5, "", StatusUp},
+		{"third transition in the window, flapping", 12 * time.Second, 0.5, SeverityWarning, StatusFlapping},
+		{"transitions are suppressed while flapping", 14 * time.Second, 0.95, "", StatusFlapping},
+		{"transitions are suppressed while flapping", 16 * time.Second, 0.95, "", StatusFlapping},
+		{"transitions left the window, stops flapping", 70 * time.Second, 0.95, SeverityInfo, StatusUp},
+	}
+
+	for _, step := range steps {
+		now := start.Add(step.offset)
+		alert, ok := getAlert(now, "https://google.com", state, 1, availability(now, step.availability), config)
+		if ok != (step.expectedSeverity != "") || alert.Severity != step.expectedSeverity {
+			t.Errorf("%v: got alert %v (%v), want severity %q", step.name, ok, alert.Severity, step.expectedSeverity)
+		}
+		if state.status() != step.expectedStatus {
+			t.Errorf("%v: got status %v, want %v", step.name, state.status(), step.expectedStatus)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	now := time.Now()
+	records := []request.ResponseLog{
+		{Timestamp: now, URL: "https://a.com", StatusCode: "200", Success: true},
+		{Timestamp: now, URL: "https://a.com", StatusCode: "500"},
+		{Timestamp: now, URL: "https://a.com", StatusCode: "200", Success: true},
+		{Timestamp: now, URL: "https://a.com", StatusCode: "503", Maintenance: true},
+	}
+
+	// 2 successes out of 3 counted checks, under the 0.8 threshold
+	if alert := Evaluate(now, "https://a.com", records, alertConfig); alert.Up || alert.Severity != SeverityCritical {
+		t.Errorf("Got %+v, want a down alert", alert)
+	}
+	records[1].Success = true
+	if alert := Evaluate(now, "https://a.com", records, alertConfig); !alert.Up || alert.Availability != 1 {
+		t.Errorf("Got %+v, want an up alert with a full availability", alert)
+	}
+}
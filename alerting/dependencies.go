@@ -0,0 +1,145 @@
+package alerting
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/monitor"
+)
+
+// dependencies maps each website to the websites it depends on (its parents)
+type dependencies map[string][]string
+
+func newDependencies(websites []monitor.Website) (dependencies, error) {
+	known := make(map[string]bool)
+	for _, ws := range websites {
+		known[ws.URL] = true
+	}
+
+	deps := make(dependencies)
+	for _, ws := range websites {
+		for _, parent := range ws.DependsOn {
+			if !known[parent] {
+				return nil, fmt.Errorf("website %v depends on %v, which is not monitored", ws.URL, parent)
+			}
+		}
+		deps[ws.URL] = ws.DependsOn
+	}
+
+	for _, ws := range websites {
+		for _, ancestor := range deps.ancestors(ws.URL) {
+			if ancestor == ws.URL {
+				return nil, fmt.Errorf("website %v depends on itself", ws.URL)
+			}
+		}
+	}
+	return deps, nil
+}
+
+// ancestors returns the websites url depends on, directly or not
+func (d dependencies) ancestors(url string) []string {
+	seen := make(map[string]bool)
+	queue := append([]string{}, d[url]...)
+	res := make([]string, 0)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if seen[current] {
+			continue
+		}
+		seen[current] = true
+		res = append(res, current)
+		queue = append(queue, d[current]...)
+	}
+	return res
+}
+
+// descendants returns the websites that depend on url, directly or not
+func (d dependencies) descendants(url string) []string {
+	res := make([]string, 0)
+	for child := range d {
+		for _, ancestor := range d.ancestors(child) {
+			if ancestor == url {
+				res = append(res, child)
+				break
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// downAncestor returns an ancestor of url that is down
+func (d dependencies) downAncestor(url string) (string, bool) {
+	for _, ancestor := range d.ancestors(url) {
+		if state, ok := states[ancestor]; ok && !state.up {
+			return ancestor, true
+		}
+	}
+	return "", false
+}
+
+// apply suppresses the alerts of websites whose parents are down,
+// and turns the down alerts of the parents into root-cause alerts listing the affected websites
+// When a parent recovers, the websites that are still down get their own alert
+// The caller must hold statesMu
+func (d dependencies) apply(t time.Time, alerts []Alert) []Alert {
+	res := make([]Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		state := states[alert.URL]
+
+		switch {
+		case !alert.Up && alert.Severity != SeverityWarning:
+			if parent, ok := d.downAncestor(alert.URL); ok {
+				alert.SuppressedBy = parent
+				state.suppressedBy = parent
+				break
+			}
+			affected := make([]string, 0)
+			for _, child := range d.descendants(alert.URL) {
+				if childState, ok := states[child]; ok && !childState.up {
+					affected = append(affected, child)
+				}
+			}
+			if len(affected) > 0 {
+				alert.Affected = affected
+				alert.Message = fmt.Sprintf("Website %v is down, affecting %v. availability = %.2f%%, time = %s\n", alert.URL, strings.Join(affected, ", "), 100*alert.Availability, t.Format(time.RFC1123))
+			}
+		case alert.Up && state.suppressedBy != "":
+			alert.SuppressedBy = state.suppressedBy
+			state.suppressedBy = ""
+		}
+		res = append(res, alert)
+
+		if alert.Up && alert.SuppressedBy == "" {
+			res = append(res, d.stillDown(t, alert.URL)...)
+		}
+	}
+	return res
+}
+
+// stillDown returns alerts for the websites suppressed by url that are still down after its recovery
+func (d dependencies) stillDown(t time.Time, url string) []Alert {
+	res := make([]Alert, 0)
+	for _, child := range d.descendants(url) {
+		state, ok := states[child]
+		if !ok || state.up || state.suppressedBy == "" {
+			continue
+		}
+		if parent, ok := d.downAncestor(child); ok {
+			state.suppressedBy = parent
+			continue
+		}
+		state.suppressedBy = ""
+		res = append(res, Alert{
+			URL:          child,
+			Availability: state.availability,
+			Time:         t,
+			Severity:     SeverityCritical,
+			Message:      fmt.Sprintf("Website %v is still down after %v recovered. availability = %.2f%%, time = %s\n", child, url, 100*state.availability, t.Format(time.RFC1123)),
+		})
+	}
+	return res
+}
@@ -0,0 +1,64 @@
+package alerting
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/monitor"
+)
+
+func TestDependencies(t *testing.T) {
+	websites := []monitor.Website{
+		{URL: "https://lb.com"},
+		{URL: "https://auth.com", DependsOn: []string{"https://lb.com"}},
+		{URL: "https://app.com", DependsOn: []string{"https://auth.com"}},
+	}
+	deps, err := newDependencies(websites)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	down := func(url string) Alert {
+		return Alert{URL: url, Time: start, Severity: SeverityCritical}
+	}
+	up := func(url string) Alert {
+		return Alert{URL: url, Up: true, Time: start, Severity: SeverityInfo}
+	}
+
+	previous := states
+	t.Cleanup(func() { states = previous })
+	states = map[string]*siteState{
+		"https://lb.com":   {up: false},
+		"https://auth.com": {up: false},
+		"https://app.com":  {up: false},
+	}
+
+	// the children are suppressed, the root cause lists them
+	alerts := deps.apply(start, []Alert{down("https://app.com"), down("https://lb.com"), down("https://auth.com")})
+	if alerts[0].SuppressedBy == "" || alerts[2].SuppressedBy == "" {
+		t.Errorf("Got %+v, want the alerts of the children to be suppressed", alerts)
+	}
+	if alerts[1].SuppressedBy != "" || !reflect.DeepEqual(alerts[1].Affected, []string{"https://app.com", "https://auth.com"}) {
+		t.Errorf("Got %+v, want a root-cause alert listing both children", alerts[1])
+	}
+
+	// the root cause recovers, a child that is still down gets its own alert
+	states["https://lb.com"].up = true
+	states["https://auth.com"].up = true
+	alerts = deps.apply(start, []Alert{up("https://lb.com"), up("https://auth.com")})
+	byURL := make(map[string]Alert)
+	for _, alert := range alerts {
+		byURL[alert.URL] = alert
+	}
+	if len(alerts) != 3 || byURL["https://lb.com"].SuppressedBy != "" || byURL["https://auth.com"].SuppressedBy == "" || byURL["https://app.com"].Up {
+		t.Errorf("Got %+v, want the recovery of the root cause, a suppressed recovery and a down alert for https://app.com", alerts)
+	}
+
+	// cycles are rejected
+	websites[0].DependsOn = []string{"https://app.com"}
+	if _, err := newDependencies(websites); err == nil {
+		t.Errorf("expected an error for a dependency cycle")
+	}
+}
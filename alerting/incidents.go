@@ -0,0 +1,48 @@
+package alerting
+
+import "time"
+
+// Incident is a period during which a website was down, End is zero for ongoing incidents
+type Incident struct {
+	URL   string    `json:"url"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Message is the message of the alert that opened the incident
+	Message string `json:"message"`
+}
+
+// Ongoing tells if the website is still down
+func (i Incident) Ongoing() bool {
+	return i.End.IsZero()
+}
+
+// Duration is the length of the incident, up to now for ongoing incidents
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.Ongoing() {
+		return now.Sub(i.Start)
+	}
+	return i.End.Sub(i.Start)
+}
+
+// Incidents pairs the down alerts with the recovery alerts that follow them, alerts must be oldest first
+// flapping warnings are ignored, and so are the alerts suppressed by a dependency, the incident of the root cause covers them
+// incidents are returned in the order they started
+func Incidents(alerts []Alert) []Incident {
+	incidents := make([]Incident, 0)
+	open := make(map[string]int)
+	for _, alert := range alerts {
+		if alert.Severity == SeverityWarning || alert.SuppressedBy != "" {
+			continue
+		}
+		i, isOpen := open[alert.URL]
+		switch {
+		case !alert.Up && !isOpen:
+			open[alert.URL] = len(incidents)
+			incidents = append(incidents, Incident{URL: alert.URL, Start: alert.Time, Message: alert.Message})
+		case alert.Up && isOpen:
+			incidents[i].End = alert.Time
+			delete(open, alert.URL)
+		}
+	}
+	return incidents
+}
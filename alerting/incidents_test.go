@@ -0,0 +1,34 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIncidents(t *testing.T) {
+	start := time.Now()
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	alerts := []Alert{
+		{URL: "https://a.com", Time: at(0), Severity: SeverityCritical},
+		{URL: "https://b.com", Time: at(1), Severity: SeverityWarning},
+		{URL: "https://a.com", Up: true, Time: at(5), Severity: SeverityInfo},
+		{URL: "https://b.com", Time: at(6), Severity: SeverityCritical},
+		{URL: "https://b.com", Time: at(7), Severity: SeverityCritical},
+		// covered by the incident of https://b.com
+		{URL: "https://c.com", Time: at(7), Severity: SeverityCritical, SuppressedBy: "https://b.com"},
+	}
+
+	incidents := Incidents(alerts)
+	if len(incidents) != 2 {
+		t.Fatalf("Got %+v, want 2 incidents", incidents)
+	}
+	if incidents[0].URL != "https://a.com" || incidents[0].Ongoing() || incidents[0].Duration(at(10)) != 5*time.Minute {
+		t.Errorf("Got %+v, want a 5 minutes incident for https://a.com", incidents[0])
+	}
+	if incidents[1].URL != "https://b.com" || !incidents[1].Ongoing() || incidents[1].Duration(at(10)) != 4*time.Minute {
+		t.Errorf("Got %+v, want an ongoing incident for https://b.com started with its first down alert", incidents[1])
+	}
+}
@@ -0,0 +1,102 @@
+package alerting
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// NotifierConfig describes a notification channel alerts can be routed to
+// Supported types are "webhook" (POSTs the alerts as JSON to URL) and "file" (appends the alerts to Path)
+type NotifierConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+	Path string `json:"path"`
+}
+
+// Notifier delivers a group of alerts to a notification channel
+type Notifier interface {
+	Notify(alerts []Alert) error
+}
+
+func newNotifiers(configs []NotifierConfig) (map[string]Notifier, error) {
+	notifiers := make(map[string]Notifier)
+	for _, c := range configs {
+		if c.Name == "" {
+			return nil, fmt.Errorf("notifier of type %q has no name", c.Type)
+		}
+		if _, ok := notifiers[c.Name]; ok {
+			return nil, fmt.Errorf("notifier %q is declared twice", c.Name)
+		}
+
+		switch c.Type {
+		case "webhook":
+			if c.URL == "" {
+				return nil, fmt.Errorf("webhook notifier %q has no url", c.Name)
+			}
+			notifiers[c.Name] = webhookNotifier{url: c.URL, client: &http.Client{Timeout: 10 * time.Second}}
+		case "file":
+			if c.Path == "" {
+				return nil, fmt.Errorf("file notifier %q has no path", c.Name)
+			}
+			notifiers[c.Name] = fileNotifier{path: c.Path}
+		default:
+			return nil, fmt.Errorf("notifier %q has an unknown type %q", c.Name, c.Type)
+		}
+	}
+	return notifiers, nil
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// Notify posts the alerts as a single JSON document
+func (w webhookNotifier) Notify(alerts []Alert) error {
+	body, err := json.Marshal(struct {
+		Alerts []Alert `json:"alerts"`
+	}{alerts})
+	if err != nil {
+		return err
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting alerts to %v: %v", w.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %v answered with status %v", w.url, resp.StatusCode)
+	}
+	return nil
+}
+
+type fileNotifier struct {
+	path string
+}
+
+// Notify appends the alert messages to the file, one group per block
+func (f fileNotifier) Notify(alerts []Alert) error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening alert file %v: %v", f.path, err)
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	for _, alert := range alerts {
+		buf.WriteString(alert.Message)
+	}
+	buf.WriteString("\n")
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("error writing to alert file %v: %v", f.path, err)
+	}
+	return nil
+}
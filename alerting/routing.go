@@ -0,0 +1,363 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/maintenance"
+)
+
+// Route decides which notifier receives the alerts matching it
+// every matching route is applied, so an alert can reach several notifiers
+// Intervals are expressed in seconds, a zero value disables the feature:
+//   - GroupWait: alerts matching the route within this delay are sent as a single notification
+//   - RepeatInterval: a down alert that was not acknowledged is sent again after this delay
+//   - EscalateAfter: a down alert that was not acknowledged is sent to EscalateTo after this delay
+type Route struct {
+	Match          RouteMatch `json:"match"`
+	Notifier       string     `json:"notifier"`
+	GroupWait      int64      `json:"groupWait"`
+	RepeatInterval int64      `json:"repeatInterval"`
+	EscalateAfter  int64      `json:"escalateAfter"`
+	EscalateTo     string     `json:"escalateTo"`
+}
+
+// RouteMatch lists the conditions an alert must meet to follow a route
+// URL is a regular expression, the website of the alert must have all the Tags, empty fields match every alert
+type RouteMatch struct {
+	URL      string            `json:"url"`
+	Severity string            `json:"severity"`
+	Tags     map[string]string `json:"tags"`
+	Group    string            `json:"group"`
+}
+
+// deliveryQueueSize is the number of notifications waiting to be sent, past it the new ones are dropped
+const deliveryQueueSize = 100
+
+// delivery is a group of alerts ready to be sent to a notifier
+type delivery struct {
+	name     string
+	notifier Notifier
+	alerts   []Alert
+}
+
+// send delivers the alerts to the notifier
+func (d delivery) send() error {
+	if err := d.notifier.Notify(d.alerts); err != nil {
+		return fmt.Errorf("notifier %v: %v", d.name, err)
+	}
+	return nil
+}
+
+// deliver sends the notifications until the context is done, away from the alert loop,
+// so a slow notifier doesn't hold back the evaluation of the websites
+func deliver(ctx context.Context, deliveries <-chan delivery, alertc chan string) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-deliveries:
+			if err := d.send(); err != nil {
+				report(ctx, alertc, red.Sprintf("Alert notification failed: %v, time = %s\n", err, time.Now().Format(time.RFC1123)))
+			}
+		}
+	}
+}
+
+// report sends a message to the alerts channel without waiting for the dashboard to read it
+func report(ctx context.Context, alertc chan string, message string) {
+	go func() {
+		select {
+		case alertc <- message:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// incident tracks a down alert until the website recovers
+// silenced incidents are notified once their silence or maintenance window is over
+type incident struct {
+	alert        Alert
+	notifiedAt   time.Time
+	escalated    bool
+	acknowledged bool
+	silenced     bool
+}
+
+type routeState struct {
+	Route
+	url        *regexp.Regexp
+	notifier   Notifier
+	escalation Notifier
+	pending    []Alert
+	flushAt    time.Time
+	recovered  []Alert
+	incidents  map[string]*incident
+}
+
+// router sits between the alert logic and the notifiers
+type router struct {
+	mu     sync.Mutex
+	routes []*routeState
+}
+
+var (
+	activeRouter   *router
+	activeRouterMu sync.Mutex
+)
+
+func setRouter(r *router) {
+	activeRouterMu.Lock()
+	defer activeRouterMu.Unlock()
+	activeRouter = r
+}
+
+// Acknowledge marks the ongoing incident of a website as acknowledged
+// acknowledged incidents are neither repeated nor escalated
+// It returns false if the website has no ongoing incident
+func Acknowledge(url string) bool {
+	activeRouterMu.Lock()
+	r := activeRouter
+	activeRouterMu.Unlock()
+
+	if r == nil {
+		return false
+	}
+	return r.acknowledge(url)
+}
+
+func newRouter(routes []Route, notifiers map[string]Notifier) (*router, error) {
+	r := &router{}
+	for i, route := range routes {
+		notifier, ok := notifiers[route.Notifier]
+		if !ok {
+			return nil, fmt.Errorf("route %d uses an unknown notifier %q", i, route.Notifier)
+		}
+		if route.GroupWait < 0 || route.RepeatInterval < 0 || route.EscalateAfter < 0 {
+			return nil, fmt.Errorf("route %d should not have negative durations", i)
+		}
+		state := &routeState{Route: route, notifier: notifier, incidents: make(map[string]*incident)}
+
+		if route.Match.URL != "" {
+			re, err := regexp.Compile(route.Match.URL)
+			if err != nil {
+				return nil, fmt.Errorf("route %d has an invalid url pattern: %v", i, err)
+			}
+			state.url = re
+		}
+
+		if route.EscalateAfter > 0 {
+			escalation, ok := notifiers[route.EscalateTo]
+			if !ok {
+				return nil, fmt.Errorf("route %d escalates to an unknown notifier %q", i, route.EscalateTo)
+			}
+			state.escalation = escalation
+		}
+		r.routes = append(r.routes, state)
+	}
+	return r, nil
+}
+
+// inherit takes over the pending alerts and incidents of the unchanged routes of a previous router
+func (r *router) inherit(previous *router) {
+	if previous == nil {
+		return
+	}
+	previous.mu.Lock()
+	defer previous.mu.Unlock()
+
+	used := make(map[*routeState]bool)
+	for _, rs := range r.routes {
+		for _, old := range previous.routes {
+			if !used[old] && reflect.DeepEqual(rs.Route, old.Route) {
+				used[old] = true
+				rs.pending = old.pending
+				rs.flushAt = old.flushAt
+				rs.recovered = old.recovered
+				rs.incidents = old.incidents
+				break
+			}
+		}
+	}
+}
+
+func (rs *routeState) matches(alert Alert) bool {
+	if rs.url != nil && !rs.url.MatchString(alert.URL) {
+		return false
+	}
+	if rs.Match.Severity != "" && rs.Match.Severity != alert.Severity {
+		return false
+	}
+	if rs.Match.Group != "" && rs.Match.Group != alert.Group {
+		return false
+	}
+	for key, value := range rs.Match.Tags {
+		if alert.Tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// dispatch queues an alert on every matching route
+func (r *router) dispatch(now time.Time, alert Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rs := range r.routes {
+		if !rs.matches(alert) {
+			continue
+		}
+
+		inc, open := rs.incidents[alert.URL]
+		notified := open && !inc.notifiedAt.IsZero()
+
+		// silenced alerts are held back, except the recovery of an incident that was already notified
+		if !alert.Silenced || (alert.Up && notified) {
+			if len(rs.pending) == 0 {
+				rs.flushAt = now.Add(time.Duration(rs.GroupWait) * time.Second)
+			}
+			rs.pending = append(rs.pending, alert)
+		}
+
+		// flapping websites don't keep an incident open, their alerts are suppressed
+		if alert.Up || alert.Severity == SeverityWarning {
+			if open && inc.escalated {
+				rs.recovered = append(rs.recovered, alert)
+			}
+			delete(rs.incidents, alert.URL)
+		} else {
+			rs.incidents[alert.URL] = &incident{alert: alert, silenced: alert.Silenced}
+		}
+	}
+}
+
+// reopen recreates the incident of a stored down alert on every matching route, after a restart
+// the alert was notified when it was raised, the incident is notified again once its silence is over if it was silenced
+// suppressed and flapping alerts don't open incidents, and the incidents already open are kept
+func (r *router) reopen(now time.Time, alert Alert) {
+	if alert.Up || alert.Severity == SeverityWarning || alert.SuppressedBy != "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rs := range r.routes {
+		if _, open := rs.incidents[alert.URL]; open || !rs.matches(alert) {
+			continue
+		}
+		inc := &incident{alert: alert, silenced: alert.Silenced && maintenance.Active(alert.URL, now)}
+		if !inc.silenced {
+			inc.notifiedAt = alert.Time
+		}
+		rs.incidents[alert.URL] = inc
+	}
+}
+
+// flush returns the grouped, repeated and escalated alerts that are due
+func (r *router) flush(now time.Time) []delivery {
+	var deliveries []delivery
+
+	r.mu.Lock()
+	for _, rs := range r.routes {
+		if len(rs.pending) > 0 && !now.Before(rs.flushAt) {
+			for _, alert := range rs.pending {
+				if inc, ok := rs.incidents[alert.URL]; ok && !alert.Up {
+					inc.notifiedAt = now
+				}
+			}
+			deliveries = append(deliveries, delivery{rs.Notifier, rs.notifier, rs.pending})
+			rs.pending = nil
+		}
+
+		var repeats, escalations []Alert
+		for url, inc := range rs.incidents {
+			if maintenance.Active(url, now) {
+				continue
+			}
+			if inc.silenced {
+				inc.silenced = false
+				// the incident was acknowledged during the silence, it's not worth a notification anymore
+				if inc.acknowledged {
+					continue
+				}
+				inc.notifiedAt = now
+				repeats = append(repeats, inc.alert)
+				continue
+			}
+			if inc.acknowledged || inc.notifiedAt.IsZero() {
+				continue
+			}
+			if rs.RepeatInterval > 0 && now.Sub(inc.notifiedAt) >= time.Duration(rs.RepeatInterval)*time.Second {
+				inc.notifiedAt = now
+				repeats = append(repeats, inc.alert)
+			}
+			if rs.escalation != nil && !inc.escalated && now.Sub(inc.alert.Time) >= time.Duration(rs.EscalateAfter)*time.Second {
+				inc.escalated = true
+				escalations = append(escalations, inc.alert)
+			}
+		}
+		escalations = append(escalations, rs.recovered...)
+		rs.recovered = nil
+
+		if len(repeats) > 0 {
+			deliveries = append(deliveries, delivery{rs.Notifier, rs.notifier, repeats})
+		}
+		if len(escalations) > 0 {
+			deliveries = append(deliveries, delivery{rs.EscalateTo, rs.escalation, escalations})
+		}
+	}
+	r.mu.Unlock()
+	return deliveries
+}
+
+// AcknowledgeAll marks every ongoing incident as acknowledged
+// It returns the websites of the acknowledged incidents
+func AcknowledgeAll() []string {
+	activeRouterMu.Lock()
+	r := activeRouter
+	activeRouterMu.Unlock()
+
+	if r == nil {
+		return nil
+	}
+	return r.acknowledgeAll()
+}
+
+func (r *router) acknowledgeAll() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	seen := make(map[string]bool)
+	urls := make([]string, 0)
+	for _, rs := range r.routes {
+		for url, inc := range rs.incidents {
+			if !inc.acknowledged && !seen[url] {
+				seen[url] = true
+				urls = append(urls, url)
+			}
+			inc.acknowledged = true
+		}
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+func (r *router) acknowledge(url string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := false
+	for _, rs := range r.routes {
+		if inc, ok := rs.incidents[url]; ok {
+			inc.acknowledged = true
+			found = true
+		}
+	}
+	return found
+}
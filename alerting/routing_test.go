@@ -0,0 +1,177 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/maintenance"
+)
+
+type recordingNotifier struct {
+	notifications [][]Alert
+}
+
+func (r *recordingNotifier) Notify(alerts []Alert) error {
+	r.notifications = append(r.notifications, alerts)
+	return nil
+}
+
+// flush sends the notifications that are due right away
+func flush(r *router, now time.Time) {
+	for _, d := range r.flush(now) {
+		d.send()
+	}
+}
+
+func TestRouter(t *testing.T) {
+	start := time.Now()
+	down := func(url string) Alert {
+		return Alert{URL: url, Time: start, Severity: SeverityCritical}
+	}
+	up := func(url string) Alert {
+		return Alert{URL: url, Up: true, Time: start, Severity: SeverityInfo}
+	}
+
+	ops := &recordingNotifier{}
+	pager := &recordingNotifier{}
+	notifiers := map[string]Notifier{"ops": ops, "pager": pager}
+	routes := []Route{
+		{Match: RouteMatch{URL: "example"}, Notifier: "ops", GroupWait: 5, RepeatInterval: 60, EscalateAfter: 120, EscalateTo: "pager"},
+	}
+
+	r, err := newRouter(routes, notifiers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// simultaneous failures are grouped, unmatched urls are ignored
+	r.dispatch(start, down("https://a.example.com"))
+	r.dispatch(start.Add(2*time.Second), down("https://b.example.com"))
+	r.dispatch(start.Add(2*time.Second), down("https://other.com"))
+	flush(r, start.Add(4*time.Second))
+	if len(ops.notifications) != 0 {
+		t.Fatalf("Got %v notifications before the group wait, want 0", len(ops.notifications))
+	}
+	flush(r, start.Add(5*time.Second))
+	if len(ops.notifications) != 1 || len(ops.notifications[0]) != 2 {
+		t.Fatalf("Got %v, want a single notification with 2 alerts", ops.notifications)
+	}
+
+	// unacknowledged alerts are repeated, acknowledged ones are not
+	if !r.acknowledge("https://b.example.com") {
+		t.Fatalf("acknowledge returned false for an ongoing incident")
+	}
+	flush(r, start.Add(65*time.Second))
+	if len(ops.notifications) != 2 || len(ops.notifications[1]) != 1 || ops.notifications[1][0].URL != "https://a.example.com" {
+		t.Fatalf("Got %v, want a repeat for https://a.example.com only", ops.notifications)
+	}
+
+	// unacknowledged alerts are escalated once
+	flush(r, start.Add(120*time.Second))
+	flush(r, start.Add(121*time.Second))
+	if len(pager.notifications) != 1 || pager.notifications[0][0].URL != "https://a.example.com" {
+		t.Fatalf("Got %v, want a single escalation for https://a.example.com", pager.notifications)
+	}
+
+	// the recovery of an escalated incident reaches the escalation channel too
+	r.dispatch(start.Add(130*time.Second), up("https://a.example.com"))
+	flush(r, start.Add(135*time.Second))
+	if len(pager.notifications) != 2 || !pager.notifications[1][0].Up {
+		t.Fatalf("Got %v, want the recovery on the escalation channel", pager.notifications)
+	}
+	if r.acknowledge("https://a.example.com") {
+		t.Fatalf("acknowledge returned true for a resolved incident")
+	}
+}
+
+func TestRouteByTags(t *testing.T) {
+	payments := &recordingNotifier{}
+	notifiers := map[string]Notifier{"payments": payments}
+	routes := []Route{{Match: RouteMatch{Tags: map[string]string{"team": "payments", "env": "prod"}}, Notifier: "payments"}}
+	r, err := newRouter(routes, notifiers)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	r.dispatch(start, Alert{URL: "https://pay.com", Time: start, Severity: SeverityCritical, Tags: map[string]string{"team": "payments", "env": "prod", "region": "eu"}})
+	r.dispatch(start, Alert{URL: "https://pay-staging.com", Time: start, Severity: SeverityCritical, Tags: map[string]string{"team": "payments", "env": "staging"}})
+	r.dispatch(start, Alert{URL: "https://blog.com", Time: start, Severity: SeverityCritical})
+	flush(r, start)
+	if len(payments.notifications) != 1 || len(payments.notifications[0]) != 1 || payments.notifications[0][0].URL != "https://pay.com" {
+		t.Errorf("Got %v, want a single notification for https://pay.com", payments.notifications)
+	}
+}
+
+func TestSilencedIncidents(t *testing.T) {
+	ops := &recordingNotifier{}
+	r, err := newRouter([]Route{{Notifier: "ops"}}, map[string]Notifier{"ops": ops})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// no silence is configured, so both silences are over at the next flush
+	start := time.Now()
+	r.dispatch(start, Alert{URL: "https://a.com", Time: start, Severity: SeverityCritical, Silenced: true})
+	r.dispatch(start, Alert{URL: "https://b.com", Time: start, Severity: SeverityCritical, Silenced: true})
+	r.acknowledge("https://b.com")
+	flush(r, start.Add(time.Second))
+	if len(ops.notifications) != 1 || len(ops.notifications[0]) != 1 || ops.notifications[0][0].URL != "https://a.com" {
+		t.Errorf("Got %v, want a notification for https://a.com only, https://b.com was acknowledged during its silence", ops.notifications)
+	}
+}
+
+func TestSilencedAlertsAreHeldBack(t *testing.T) {
+	start := time.Now()
+	if err := maintenance.Set(maintenance.Config{Silences: []maintenance.Silence{{URL: `silenced\.com`, Start: start, End: start.Add(time.Hour)}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { maintenance.Set(maintenance.Config{}) })
+
+	ops := &recordingNotifier{}
+	r, err := newRouter([]Route{{Notifier: "ops"}}, map[string]Notifier{"ops": ops})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.dispatch(start, Alert{URL: "https://silenced.com", Time: start, Severity: SeverityCritical, Silenced: true})
+	r.dispatch(start, Alert{URL: "https://flaky.silenced.com", Time: start, Severity: SeverityCritical, Silenced: true})
+	r.dispatch(start.Add(time.Minute), Alert{URL: "https://flaky.silenced.com", Up: true, Time: start.Add(time.Minute), Severity: SeverityInfo, Silenced: true})
+	flush(r, start.Add(time.Second))
+	flush(r, start.Add(30*time.Minute))
+	if len(ops.notifications) != 0 {
+		t.Fatalf("Got %v during the silence, want no notification", ops.notifications)
+	}
+
+	// the incident still open at the end of the silence is notified, the one that recovered meanwhile isn't
+	flush(r, start.Add(61*time.Minute))
+	if len(ops.notifications) != 1 || len(ops.notifications[0]) != 1 || ops.notifications[0][0].URL != "https://silenced.com" {
+		t.Errorf("Got %v, want a notification for https://silenced.com after the silence", ops.notifications)
+	}
+}
+
+func TestReopenedIncidents(t *testing.T) {
+	ops := &recordingNotifier{}
+	r, err := newRouter([]Route{{Notifier: "ops", RepeatInterval: 60}}, map[string]Notifier{"ops": ops})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// alerts stored before a restart
+	start := time.Now()
+	r.reopen(start, Alert{URL: "https://a.com", Time: start.Add(-30 * time.Second), Severity: SeverityCritical})
+	r.reopen(start, Alert{URL: "https://b.com", Time: start.Add(-30 * time.Second), Severity: SeverityCritical, SuppressedBy: "https://a.com"})
+	r.reopen(start, Alert{URL: "https://c.com", Time: start.Add(-30 * time.Second), Severity: SeverityWarning})
+
+	flush(r, start)
+	if len(ops.notifications) != 0 {
+		t.Fatalf("Got %v, want no notification: the alert was notified before the restart", ops.notifications)
+	}
+	flush(r, start.Add(30*time.Second))
+	if len(ops.notifications) != 1 || len(ops.notifications[0]) != 1 || ops.notifications[0][0].URL != "https://a.com" {
+		t.Errorf("Got %v, want a repeat for https://a.com only", ops.notifications)
+	}
+	if !r.acknowledge("https://a.com") || r.acknowledge("https://b.com") || r.acknowledge("https://c.com") {
+		t.Errorf("want only the incident of https://a.com to be reopened")
+	}
+}
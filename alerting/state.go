@@ -0,0 +1,134 @@
+package alerting
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/database"
+)
+
+// Website states as seen by the alert logic
+const (
+	StatusUp       string = "up"
+	StatusDown     string = "down"
+	StatusFlapping string = "flapping"
+)
+
+// siteState keeps track of the state of a website we're monitoring
+type siteState struct {
+	up bool
+	// pendingSince is when the website first met the condition to change state
+	pendingSince time.Time
+	// transitions are the times of the last state changes, used for flap detection
+	transitions []time.Time
+	flapping    bool
+	// suppressedBy is the down parent that suppressed the last down alert of the website
+	suppressedBy string
+	// availability is the last availability computed for the website
+	availability float64
+}
+
+var (
+	states   map[string]*siteState = make(map[string]*siteState)
+	statesMu sync.RWMutex
+)
+
+// Status returns the state of a website: StatusUp, StatusDown or StatusFlapping
+// the boolean is false if the alert logic doesn't know the website
+func Status(url string) (string, bool) {
+	statesMu.RLock()
+	defer statesMu.RUnlock()
+
+	state, ok := states[url]
+	if !ok {
+		return "", false
+	}
+	return state.status(), true
+}
+
+// StatusAt returns the state of a website at a past time, from its last stored alert: StatusUp or StatusDown
+// websites that had no alert yet are up
+func StatusAt(url string, t time.Time) (string, error) {
+	event, ok, err := database.GetLastAlertEvent(url, t)
+	if err != nil {
+		return "", err
+	}
+	if ok && !event.Up {
+		return StatusDown, nil
+	}
+	return StatusUp, nil
+}
+
+func (s *siteState) status() string {
+	switch {
+	case s.flapping:
+		return StatusFlapping
+	case s.up:
+		return StatusUp
+	default:
+		return StatusDown
+	}
+}
+
+// recordTransition remembers a state change and updates the flapping flag
+func (s *siteState) recordTransition(t time.Time, alertConfig AlertConfig) {
+	if alertConfig.FlapWindow <= 0 || alertConfig.FlapThreshold <= 0 {
+		return
+	}
+	s.transitions = append(s.transitions, t)
+	s.pruneTransitions(t, alertConfig)
+	s.flapping = s.flapping || len(s.transitions) >= alertConfig.FlapThreshold
+}
+
+// pruneTransitions forgets the state changes that are out of the flap window
+// it returns true if the website stopped flapping
+func (s *siteState) pruneTransitions(t time.Time, alertConfig AlertConfig) bool {
+	window := time.Duration(alertConfig.FlapWindow) * time.Second
+	kept := s.transitions[:0]
+	for _, transition := range s.transitions {
+		if t.Sub(transition) < window {
+			kept = append(kept, transition)
+		}
+	}
+	s.transitions = kept
+
+	if s.flapping && len(s.transitions) < alertConfig.FlapThreshold {
+		s.flapping = false
+		return true
+	}
+	return false
+}
+
+// restoreState sets the state of each new website to the one of its last stored alert
+// websites that never had an alert start as up, websites that are not in urls anymore are forgotten
+// it returns the last alerts of the new websites restored as down, so their incidents can be reopened
+func restoreState(urls []string) ([]Alert, error) {
+	statesMu.Lock()
+	defer statesMu.Unlock()
+
+	wanted := make(map[string]bool)
+	for _, url := range urls {
+		wanted[url] = true
+	}
+	for url := range states {
+		if !wanted[url] {
+			delete(states, url)
+		}
+	}
+
+	down := make([]Alert, 0)
+	for _, url := range urls {
+		if _, ok := states[url]; ok {
+			continue
+		}
+		event, ok, err := database.GetLastAlertEvent(url, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		states[url] = &siteState{up: !ok || event.Up}
+		if ok && !event.Up {
+			down = append(down, fromEvent(event))
+		}
+	}
+	return down, nil
+}
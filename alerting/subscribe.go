@@ -0,0 +1,40 @@
+package alerting
+
+import "sync"
+
+var (
+	subscribers   map[chan Alert]bool = make(map[chan Alert]bool)
+	subscribersMu sync.Mutex
+)
+
+// Subscribe returns a channel receiving every new alert, and a function ending the subscription
+// the alert logic doesn't wait for slow subscribers, they miss the alerts that don't fit in their buffer
+func Subscribe(buffer int) (<-chan Alert, func()) {
+	c := make(chan Alert, buffer)
+
+	subscribersMu.Lock()
+	subscribers[c] = true
+	subscribersMu.Unlock()
+
+	return c, func() {
+		subscribersMu.Lock()
+		defer subscribersMu.Unlock()
+		if subscribers[c] {
+			delete(subscribers, c)
+			close(c)
+		}
+	}
+}
+
+// publish sends an alert to the subscribers
+func publish(alert Alert) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+
+	for c := range subscribers {
+		select {
+		case c <- alert:
+		default:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/secret"
+)
+
+// Config tells where the API listens: Listen is a TCP address (e.g. "127.0.0.1:8081"), Socket the path of a Unix socket
+// the API is disabled when both are empty
+type Config struct {
+	Listen string `json:"listen"`
+	Socket string `json:"socket"`
+}
+
+// Enabled tells if the API should be started
+func (c Config) Enabled() bool {
+	return c.Listen != "" || c.Socket != ""
+}
+
+// Run serves the API until the context is done
+func Run(ctx context.Context, config Config, sites Sites) error {
+	listener, err := listen(config)
+	if err != nil {
+		return fmt.Errorf("error starting the API: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	registerWebsites(mux, sites)
+	registerQueries(mux, sites)
+
+	server := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("API server error: %v", err)
+	}
+	return nil
+}
+
+func listen(config Config) (net.Listener, error) {
+	if config.Socket != "" {
+		// a socket left by a previous run would make the listen fail
+		if err := os.Remove(config.Socket); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		return net.Listen("unix", config.Socket)
+	}
+	return net.Listen("tcp", config.Listen)
+}
+
+// WriteJSON writes a value as indented JSON, with the secrets it contains redacted
+// HTML characters are not escaped, so the secrets of the URLs are found as they were registered
+// it is also used by the commands printing JSON reports
+func WriteJSON(w io.Writer, v interface{}) error {
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, secret.Redact(content.String()))
+	return err
+}
+
+// writeJSON sends a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	WriteJSON(w, v)
+}
+
+// writeError sends an error as a JSON response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
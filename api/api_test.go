@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
+)
+
+type staticSites struct {
+	websites []monitor.Website
+}
+
+func (s staticSites) Websites() []monitor.Website                        { return s.websites }
+func (s staticSites) Add(website monitor.Website, persist bool) error    { return nil }
+func (s staticSites) Update(website monitor.Website, persist bool) error { return nil }
+func (s staticSites) Remove(url string, persist bool) error              { return nil }
+func (s staticSites) SetPaused(url string, paused bool, persist bool) error {
+	return nil
+}
+
+func TestResponsesAreRedacted(t *testing.T) {
+	secret.Register("api-test-token-789")
+	mux := http.NewServeMux()
+	registerWebsites(mux, staticSites{[]monitor.Website{{URL: "https://example.com/?a=1&token=api-test-token-789", CheckInterval: 5}}})
+
+	res := httptest.NewRecorder()
+	mux.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/websites", nil))
+	body := res.Body.String()
+	if res.Code != http.StatusOK || !strings.Contains(body, `"url": "https://example.com/?a=1&token=****"`) {
+		t.Errorf("Got %v %v, want the website with its token redacted", res.Code, body)
+	}
+}
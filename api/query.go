@@ -0,0 +1,214 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/database"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+)
+
+// defaultTimeFrame is the timeframe of the queries that don't give one, in seconds
+const defaultTimeFrame int64 = 600
+
+// defaultAlertLimit is the number of alerts returned by the history when no limit is given
+const defaultAlertLimit = 100
+
+// statsResponse is the JSON form of statsagent.WebsiteStats, durations are in milliseconds
+type statsResponse struct {
+	URL                string         `json:"url"`
+	Availability       float64        `json:"availability"`
+	AvgResponseTime    float64        `json:"avgResponseTimeMs"`
+	MaxResponseTime    float64        `json:"maxResponseTimeMs"`
+	AvgTimeToFirstByte float64        `json:"avgTimeToFirstByteMs"`
+	MaxTimeToFirstByte float64        `json:"maxTimeToFirstByteMs"`
+	StatusCodeCount    map[string]int `json:"statusCodes"`
+}
+
+// checkResponse is the JSON form of request.ResponseLog, durations are in milliseconds
+type checkResponse struct {
+	Timestamp   time.Time `json:"timestamp"`
+	URL         string    `json:"url"`
+	StatusCode  string    `json:"statusCode"`
+	Success     bool      `json:"success"`
+	TTFB        float64   `json:"ttfbMs"`
+	LoadTime    float64   `json:"loadTimeMs"`
+	Maintenance bool      `json:"maintenance"`
+}
+
+// stateResponse is the current state of a website as seen by the alert logic
+type stateResponse struct {
+	URL    string `json:"url"`
+	Status string `json:"status"`
+}
+
+// registerQueries adds the read-only endpoints:
+//
+//	GET /stats?timeframe=&origin=&url=   stats of the websites (all of them when no url is given)
+//	GET /checks?url=&timeframe=&origin=  raw checks of a website
+//	GET /alerts/state                    current state of each website
+//	GET /alerts?limit=                   last alerts, oldest first
+//
+// timeframe is in seconds (default 600), origin is a RFC 3339 time (default now)
+func registerQueries(mux *http.ServeMux, sites Sites) {
+	mux.HandleFunc("/stats", readOnly(func(w http.ResponseWriter, r *http.Request) {
+		origin, timeframe, err := parseRange(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		urls := r.URL.Query()["url"]
+		if len(urls) == 0 {
+			urls = monitoredURLs(sites)
+		}
+		for _, url := range urls {
+			if !monitored(sites, url) {
+				writeError(w, http.StatusNotFound, fmt.Errorf("website %v is not monitored", url))
+				return
+			}
+		}
+
+		stats, err := statsagent.GetStats(urls, origin, timeframe)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		res := make([]statsResponse, 0, len(urls))
+		for _, url := range urls {
+			s := stats[url]
+			res = append(res, statsResponse{
+				URL:                url,
+				Availability:       s.Availability,
+				AvgResponseTime:    statsagent.Milliseconds(s.AvgResponseTime),
+				MaxResponseTime:    statsagent.Milliseconds(s.MaxResponseTime),
+				AvgTimeToFirstByte: statsagent.Milliseconds(s.AvgTimeToFirstByte),
+				MaxTimeToFirstByte: statsagent.Milliseconds(s.MaxTimeToFirstByte),
+				StatusCodeCount:    s.StatusCodeCount,
+			})
+		}
+		writeJSON(w, http.StatusOK, res)
+	}))
+
+	mux.HandleFunc("/checks", readOnly(func(w http.ResponseWriter, r *http.Request) {
+		origin, timeframe, err := parseRange(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		url := r.URL.Query().Get("url")
+		if url == "" {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("the url parameter is required"))
+			return
+		}
+		if !monitored(sites, url) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("website %v is not monitored", url))
+			return
+		}
+
+		records, err := database.GetRecordsForURL(url, origin, timeframe)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		res := make([]checkResponse, 0, len(records))
+		for _, record := range records {
+			res = append(res, checkResponse{
+				Timestamp:   record.Timestamp,
+				URL:         record.URL,
+				StatusCode:  record.StatusCode,
+				Success:     record.Success,
+				TTFB:        statsagent.Milliseconds(record.TTFB),
+				LoadTime:    statsagent.Milliseconds(record.LoadTime),
+				Maintenance: record.Maintenance,
+			})
+		}
+		writeJSON(w, http.StatusOK, res)
+	}))
+
+	mux.HandleFunc("/alerts/state", readOnly(func(w http.ResponseWriter, r *http.Request) {
+		res := make([]stateResponse, 0)
+		for _, url := range monitoredURLs(sites) {
+			if status, ok := alerting.Status(url); ok {
+				res = append(res, stateResponse{URL: url, Status: status})
+			}
+		}
+		writeJSON(w, http.StatusOK, res)
+	}))
+
+	mux.HandleFunc("/alerts", readOnly(func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultAlertLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			l, err := strconv.Atoi(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", v))
+				return
+			}
+			limit = l
+		}
+
+		alerts, err := alerting.History(limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, alerts)
+	}))
+}
+
+// readOnly rejects the requests that are not GET
+func readOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// parseRange reads the origin and timeframe query parameters
+func parseRange(r *http.Request) (time.Time, int64, error) {
+	origin := time.Now()
+	if v := r.URL.Query().Get("origin"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, 0, fmt.Errorf("invalid origin %q, expected a RFC 3339 time", v)
+		}
+		origin = t
+	}
+
+	timeframe := defaultTimeFrame
+	if v := r.URL.Query().Get("timeframe"); v != "" {
+		tf, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || tf <= 0 {
+			return time.Time{}, 0, fmt.Errorf("invalid timeframe %q, expected a positive number of seconds", v)
+		}
+		timeframe = tf
+	}
+	return origin, timeframe, nil
+}
+
+// monitoredURLs returns the URLs of the websites that are not paused
+func monitoredURLs(sites Sites) []string {
+	urls := make([]string, 0)
+	for _, ws := range sites.Websites() {
+		if !ws.Paused {
+			urls = append(urls, ws.URL)
+		}
+	}
+	return urls
+}
+
+// monitored tells if a URL is the one of a website that is not paused,
+// the URLs of the queries name InfluxDB measurements so only these ones are accepted
+func monitored(sites Sites, url string) bool {
+	for _, u := range monitoredURLs(sites) {
+		if u == url {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/maintenance"
+	"github.com/ayoubed/datadog-home-project/monitor"
+)
+
+// Sites manages the monitored websites
+// persist tells if the change should be written back to the config file
+type Sites interface {
+	Websites() []monitor.Website
+	Add(website monitor.Website, persist bool) error
+	Update(website monitor.Website, persist bool) error
+	Remove(url string, persist bool) error
+	SetPaused(url string, paused bool, persist bool) error
+}
+
+// registerWebsites adds the management endpoints, websites are designated by the "url" query parameter:
+//
+//	GET    /websites                    list the websites
+//	POST   /websites                    add the website in the body
+//	PUT    /websites?url=               replace the settings of a website with the body
+//	DELETE /websites?url=               remove a website
+//	POST   /websites/pause?url=         pause the checks of a website
+//	POST   /websites/resume?url=        resume the checks of a website
+//	POST   /incidents/acknowledge?url=  acknowledge the ongoing incident of a website
+//	GET    /silences                    list the active silences
+//	POST   /silences                    add the silence in the body
+//
+// Changes are written to the config file when the "persist" query parameter is "true"
+func registerWebsites(mux *http.ServeMux, sites Sites) {
+	mux.HandleFunc("/websites", func(w http.ResponseWriter, r *http.Request) {
+		persist := r.URL.Query().Get("persist") == "true"
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, sites.Websites())
+		case http.MethodPost:
+			var website monitor.Website
+			if err := json.NewDecoder(r.Body).Decode(&website); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid website: %v", err))
+				return
+			}
+			writeResult(w, http.StatusCreated, website, sites.Add(website, persist))
+		case http.MethodPut:
+			var website monitor.Website
+			if err := json.NewDecoder(r.Body).Decode(&website); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid website: %v", err))
+				return
+			}
+			website.URL = r.URL.Query().Get("url")
+			writeResult(w, http.StatusOK, website, sites.Update(website, persist))
+		case http.MethodDelete:
+			writeResult(w, http.StatusNoContent, nil, sites.Remove(r.URL.Query().Get("url"), persist))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		}
+	})
+
+	for path, paused := range map[string]bool{"/websites/pause": true, "/websites/resume": false} {
+		paused := paused
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+				return
+			}
+			persist := r.URL.Query().Get("persist") == "true"
+			writeResult(w, http.StatusNoContent, nil, sites.SetPaused(r.URL.Query().Get("url"), paused, persist))
+		})
+	}
+
+	mux.HandleFunc("/incidents/acknowledge", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+			return
+		}
+		if !alerting.Acknowledge(r.URL.Query().Get("url")) {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no ongoing incident for %q", r.URL.Query().Get("url")))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("/silences", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, maintenance.Silences())
+		case http.MethodPost:
+			var silence maintenance.Silence
+			if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid silence: %v", err))
+				return
+			}
+			if silence.Start.IsZero() {
+				silence.Start = time.Now()
+			}
+			writeResult(w, http.StatusCreated, silence, maintenance.AddSilence(silence))
+		default:
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v not allowed", r.Method))
+		}
+	})
+}
+
+// writeResult sends the outcome of a change
+func writeResult(w http.ResponseWriter, status int, v interface{}, err error) {
+	switch {
+	case err == monitor.ErrUnknownWebsite:
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+	case v == nil:
+		w.WriteHeader(status)
+	default:
+		writeJSON(w, status, v)
+	}
+}
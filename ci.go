@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/api"
+	"github.com/ayoubed/datadog-home-project/config"
+	"github.com/ayoubed/datadog-home-project/maintenance"
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+)
+
+// ciResult is the outcome of the checks of a website in CI mode, durations are in milliseconds
+type ciResult struct {
+	URL             string         `json:"url"`
+	Passed          bool           `json:"passed"`
+	Skipped         bool           `json:"skipped"`
+	Checks          int            `json:"checks"`
+	Availability    float64        `json:"availability"`
+	AvgResponseTime float64        `json:"avgResponseTimeMs"`
+	MaxResponseTime float64        `json:"maxResponseTimeMs"`
+	StatusCodeCount map[string]int `json:"statusCodes"`
+	Message         string         `json:"message"`
+	Duration        time.Duration  `json:"-"`
+}
+
+// ciReport is the JSON report of a CI run
+type ciReport struct {
+	Time     time.Time  `json:"time"`
+	Passed   bool       `json:"passed"`
+	Websites []ciResult `json:"websites"`
+}
+
+// junitTestSuites is the JUnit XML report of a CI run, each website is a test case
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// ciCommand checks every website of the config a number of times or for a duration,
+// evaluates the alert rules once on the results and fails if a website is down
+// a website whose checks all fall in a maintenance window is skipped
+// it doesn't need a database, so it can run right after a deployment
+func ciCommand(args []string) error {
+	flags := flag.NewFlagSet("ci", flag.ExitOnError)
+	configFile := flags.String("config", defaultConfig, "config file, in JSON, YAML or TOML")
+	count := flags.Int("count", 5, "number of checks of each website")
+	duration := flags.Duration("duration", 0, "check the websites for this long instead of -count times")
+	interval := flags.Duration("interval", time.Second, "time between two checks of a website")
+	junitFile := flags.String("junit", "", "write a JUnit XML report to this file")
+	jsonFile := flags.String("json", "", "write a JSON report to this file")
+	flags.Parse(args)
+
+	if *count <= 0 && *duration <= 0 {
+		return fmt.Errorf("-count or -duration should be positive")
+	}
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("error loading config: %v", err)
+	}
+	if err := maintenance.Set(cfg.Maintenance); err != nil {
+		return fmt.Errorf("error setting up the maintenance windows: %v", err)
+	}
+
+	start := time.Now()
+	urls := configURLs(cfg, "")
+	expectedStatus := make(map[string]int)
+	for _, ws := range cfg.Websites {
+		expectedStatus[ws.URL] = ws.ExpectedStatus
+	}
+	results := make([]ciResult, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			siteStart := time.Now()
+			records := probe(url, expectedStatus[url], *count, *duration, *interval)
+			results[i] = summarize(url, records, alerting.Evaluate(time.Now(), url, records, cfg.Alert))
+			results[i].Duration = time.Since(siteStart)
+		}(i, url)
+	}
+	wg.Wait()
+
+	report := ciReport{Time: start, Passed: true, Websites: results}
+	failed := 0
+	for _, result := range results {
+		if !result.Passed {
+			report.Passed = false
+			failed++
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tCHECKS\tAVAILABILITY\tAVG RESPONSE\tMAX RESPONSE\tSTATUS CODES\tRESULT")
+	for _, r := range results {
+		result := "PASS"
+		switch {
+		case r.Skipped:
+			result = "SKIP"
+		case !r.Passed:
+			result = "FAIL"
+		}
+		fmt.Fprintf(w, "%v\t%d\t%.2f%%\t%.0fms\t%.0fms\t%v\t%v\n", r.URL, r.Checks, 100*r.Availability, r.AvgResponseTime, r.MaxResponseTime, statsagent.FormatStatusCodes(r.StatusCodeCount), result)
+	}
+	w.Flush()
+
+	if *jsonFile != "" {
+		f, err := os.Create(*jsonFile)
+		if err != nil {
+			return fmt.Errorf("error writing the JSON report: %v", err)
+		}
+		defer f.Close()
+		if err := api.WriteJSON(f, report); err != nil {
+			return fmt.Errorf("error writing the JSON report: %v", err)
+		}
+	}
+	if *junitFile != "" {
+		if err := writeJUnit(*junitFile, report, time.Since(start)); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d websites failed", failed, len(results))
+	}
+	return nil
+}
+
+// probe checks a website count times, or for the given duration when it is positive
+// requests that fail before getting a response count as failed checks
+func probe(url string, expectedStatus int, count int, duration time.Duration, interval time.Duration) []request.ResponseLog {
+	records := make([]request.ResponseLog, 0)
+	deadline := time.Now().Add(duration)
+	for i := 0; ; i++ {
+		if (duration > 0 && !time.Now().Before(deadline)) || (duration <= 0 && i >= count) {
+			return records
+		}
+		if i > 0 {
+			time.Sleep(interval)
+		}
+
+		t := time.Now()
+		log, err := request.Send(t, url, expectedStatus)
+		if err != nil {
+			log = request.ResponseLog{Timestamp: t, URL: url, StatusCode: err.Error()}
+		}
+		log.Maintenance = maintenance.Active(url, t)
+		records = append(records, log)
+	}
+}
+
+// summarize computes the result of the checks of a website, it is skipped when they all fall in a maintenance window
+func summarize(url string, records []request.ResponseLog, alert alerting.Alert) ciResult {
+	result := ciResult{URL: url, Passed: alert.Up, Checks: len(records), Availability: alert.Availability, StatusCodeCount: make(map[string]int), Message: strings.TrimSpace(alert.Message)}
+	var sum time.Duration
+	successes, counted := 0, 0
+	for _, record := range records {
+		if !record.Maintenance {
+			counted++
+		}
+		result.StatusCodeCount[record.StatusCode]++
+		if record.Success {
+			successes++
+			sum += record.LoadTime
+			if ms := statsagent.Milliseconds(record.LoadTime); ms > result.MaxResponseTime {
+				result.MaxResponseTime = ms
+			}
+		}
+	}
+	if successes > 0 {
+		result.AvgResponseTime = statsagent.Milliseconds(sum / time.Duration(successes))
+	}
+	if len(records) > 0 && counted == 0 {
+		result.Passed, result.Skipped = true, true
+		result.Message = fmt.Sprintf("Website %v was skipped, its %d checks fell in a maintenance window", url, len(records))
+	}
+	return result
+}
+
+func writeJUnit(path string, report ciReport, elapsed time.Duration) error {
+	suite := junitTestSuite{Name: "websites", Tests: len(report.Websites), Time: fmt.Sprintf("%.3f", elapsed.Seconds()), Timestamp: report.Time.Format(time.RFC3339)}
+	for _, r := range report.Websites {
+		testCase := junitTestCase{Name: r.URL, Classname: "websites", Time: fmt.Sprintf("%.3f", r.Duration.Seconds()), SystemOut: r.Message}
+		if r.Skipped {
+			suite.Skipped++
+			testCase.Skipped = &junitSkipped{Message: r.Message}
+		}
+		if !r.Passed {
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("availability %.2f%% after %d checks", 100*r.Availability, r.Checks),
+				Content: fmt.Sprintf("%v\nstatus codes: %v", r.Message, statsagent.FormatStatusCodes(r.StatusCodeCount)),
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	content, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, append([]byte(xml.Header), append(content, '\n')...), 0644); err != nil {
+		return fmt.Errorf("error writing the JUnit report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/api"
+	"github.com/ayoubed/datadog-home-project/config"
+	"github.com/ayoubed/datadog-home-project/database"
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+)
+
+// defaultRange is the time range of report and export when -from is not given
+const defaultRange = time.Hour
+
+// checkCommand probes a URL once and prints the timing breakdown of the request
+// it fails if the website is down, so it can be used in scripts
+// the expected status is the one of -status, or the one of the website in the config file when it is there, or 200
+func checkCommand(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	configFile := flags.String("config", defaultConfig, "config file, in JSON, YAML or TOML, to read the expected status of the website from")
+	status := flags.Int("status", 0, "expected status code (default the one of the config, or 200)")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: check [flags] <url>\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	url := flags.Arg(0)
+
+	expectedStatus := *status
+	if expectedStatus == 0 {
+		if _, err := os.Stat(*configFile); err == nil {
+			cfg, err := config.Load(*configFile)
+			if err != nil {
+				return fmt.Errorf("error loading config: %v", err)
+			}
+			for _, ws := range cfg.Websites {
+				if ws.URL == url {
+					expectedStatus = ws.ExpectedStatus
+				}
+			}
+		}
+	}
+
+	log, timings, err := request.Inspect(time.Now(), url, expectedStatus)
+	if err != nil {
+		return fmt.Errorf("error checking %v: %v", url, err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "URL\t%v\n", url)
+	fmt.Fprintf(w, "Status\t%v\n", log.StatusCode)
+	fmt.Fprintf(w, "DNS lookup\t%v\n", timings.DNS.Round(time.Microsecond))
+	fmt.Fprintf(w, "TCP connect\t%v\n", timings.Connect.Round(time.Microsecond))
+	fmt.Fprintf(w, "TLS handshake\t%v\n", timings.TLS.Round(time.Microsecond))
+	fmt.Fprintf(w, "Time to first byte\t%v\n", timings.TTFB.Round(time.Microsecond))
+	fmt.Fprintf(w, "Total\t%v\n", timings.Total.Round(time.Microsecond))
+	if !log.CertExpiry.IsZero() {
+		fmt.Fprintf(w, "Certificate expiry\t%v (in %d days)\n", log.CertExpiry.Format(time.RFC1123), int(time.Until(log.CertExpiry).Hours()/24))
+	}
+	w.Flush()
+
+	if !log.Success {
+		return fmt.Errorf("%v is down: %v", url, log.StatusCode)
+	}
+	return nil
+}
+
+// validateCommand loads a config file and reports the first problem found
+func validateCommand(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	configFile := flags.String("config", defaultConfig, "config file, in JSON, YAML or TOML")
+	flags.Parse(args)
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		return fmt.Errorf("%v is invalid: %v", *configFile, err)
+	}
+	fmt.Printf("%v is valid: %d websites, %d dashboard views, %d notifiers\n", *configFile, len(cfg.Websites), len(cfg.Dashboard), len(cfg.Alert.Notifiers))
+	return nil
+}
+
+// reportCommand prints the stats of the websites for a time range
+func reportCommand(args []string) error {
+	flags := flag.NewFlagSet("report", flag.ExitOnError)
+	configFile := flags.String("config", defaultConfig, "config file, in JSON, YAML or TOML")
+	from := flags.String("from", "", "start of the range, a RFC 3339 time or a duration before -to (default 1h)")
+	to := flags.String("to", "", "end of the range, a RFC 3339 time (default now)")
+	url := flags.String("url", "", "only report this website")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+
+	cfg, err := loadWithDatabase(*configFile)
+	if err != nil {
+		return err
+	}
+	start, end, err := parseTimeRange(*from, *to)
+	if err != nil {
+		return err
+	}
+	urls := configURLs(cfg, *url)
+
+	stats, err := statsagent.GetStats(urls, end, int64(end.Sub(start)/time.Second))
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		type siteReport struct {
+			URL                string         `json:"url"`
+			Availability       float64        `json:"availability"`
+			AvgResponseTime    float64        `json:"avgResponseTimeMs"`
+			MaxResponseTime    float64        `json:"maxResponseTimeMs"`
+			AvgTimeToFirstByte float64        `json:"avgTimeToFirstByteMs"`
+			MaxTimeToFirstByte float64        `json:"maxTimeToFirstByteMs"`
+			StatusCodeCount    map[string]int `json:"statusCodes"`
+		}
+		res := make([]siteReport, 0, len(urls))
+		for _, url := range urls {
+			s := stats[url]
+			res = append(res, siteReport{url, s.Availability, statsagent.Milliseconds(s.AvgResponseTime), statsagent.Milliseconds(s.MaxResponseTime), statsagent.Milliseconds(s.AvgTimeToFirstByte), statsagent.Milliseconds(s.MaxTimeToFirstByte), s.StatusCodeCount})
+		}
+		return api.WriteJSON(os.Stdout, res)
+	}
+
+	fmt.Printf("From %v to %v\n\n", start.Format(time.RFC1123), end.Format(time.RFC1123))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "URL\tAVAILABILITY\tAVG RESPONSE\tMAX RESPONSE\tAVG TTFB\tMAX TTFB\tSTATUS CODES")
+	for _, url := range urls {
+		s := stats[url]
+		fmt.Fprintf(w, "%v\t%.2f%%\t%v\t%v\t%v\t%v\t%v\n", url, 100*s.Availability, s.AvgResponseTime.Round(time.Millisecond), s.MaxResponseTime.Round(time.Millisecond), s.AvgTimeToFirstByte.Round(time.Millisecond), s.MaxTimeToFirstByte.Round(time.Millisecond), statsagent.FormatStatusCodes(s.StatusCodeCount))
+	}
+	return w.Flush()
+}
+
+// exportCommand dumps the stored checks or alerts of a time range as JSON or CSV
+func exportCommand(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	configFile := flags.String("config", defaultConfig, "config file, in JSON, YAML or TOML")
+	kind := flags.String("kind", "checks", "what to export: checks or alerts")
+	from := flags.String("from", "", "start of the range, a RFC 3339 time or a duration before -to (default 1h)")
+	to := flags.String("to", "", "end of the range, a RFC 3339 time (default now)")
+	url := flags.String("url", "", "only export this website")
+	format := flags.String("format", "json", "output format: json or csv")
+	output := flags.String("output", "-", "output file, - for the standard output")
+	flags.Parse(args)
+
+	if *kind != "checks" && *kind != "alerts" {
+		return fmt.Errorf("unknown kind %q, expected checks or alerts", *kind)
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q, expected json or csv", *format)
+	}
+
+	cfg, err := loadWithDatabase(*configFile)
+	if err != nil {
+		return err
+	}
+	start, end, err := parseTimeRange(*from, *to)
+	if err != nil {
+		return err
+	}
+
+	var header []string
+	var rows [][]string
+	var values interface{}
+	if *kind == "checks" {
+		checks := make([]exportedCheck, 0)
+		for _, u := range configURLs(cfg, *url) {
+			records, err := database.GetRecordsForURL(u, end, int64(end.Sub(start)/time.Second))
+			if err != nil {
+				return err
+			}
+			for _, record := range records {
+				checks = append(checks, exportedCheck{record.Timestamp, record.URL, record.StatusCode, record.Success, statsagent.Milliseconds(record.TTFB), statsagent.Milliseconds(record.LoadTime), record.Maintenance})
+			}
+		}
+		sort.SliceStable(checks, func(i, j int) bool { return checks[i].Timestamp.Before(checks[j].Timestamp) })
+
+		header = []string{"timestamp", "url", "statusCode", "success", "ttfbMs", "loadTimeMs", "maintenance"}
+		for _, c := range checks {
+			rows = append(rows, []string{c.Timestamp.Format(time.RFC3339Nano), c.URL, c.StatusCode, strconv.FormatBool(c.Success), formatFloat(c.TTFB), formatFloat(c.LoadTime), strconv.FormatBool(c.Maintenance)})
+		}
+		values = checks
+	} else {
+		history, err := alerting.History(0)
+		if err != nil {
+			return err
+		}
+		alerts := make([]alerting.Alert, 0)
+		for _, alert := range history {
+			if alert.Time.Before(start) || alert.Time.After(end) || (*url != "" && alert.URL != *url) {
+				continue
+			}
+			alerts = append(alerts, alert)
+		}
+
+		header = []string{"time", "url", "up", "availability", "severity", "message"}
+		for _, a := range alerts {
+			rows = append(rows, []string{a.Time.Format(time.RFC3339Nano), a.URL, strconv.FormatBool(a.Up), formatFloat(a.Availability), a.Severity, strings.TrimSpace(a.Message)})
+		}
+		values = alerts
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("error creating %v: %v", *output, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if *format == "json" {
+		return api.WriteJSON(out, values)
+	}
+	w := csv.NewWriter(out)
+	w.Write(header)
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+// exportedCheck is the exported form of request.ResponseLog, durations are in milliseconds
+type exportedCheck struct {
+	Timestamp   time.Time `json:"timestamp"`
+	URL         string    `json:"url"`
+	StatusCode  string    `json:"statusCode"`
+	Success     bool      `json:"success"`
+	TTFB        float64   `json:"ttfbMs"`
+	LoadTime    float64   `json:"loadTimeMs"`
+	Maintenance bool      `json:"maintenance"`
+}
+
+// parseTimeRange reads the -from and -to flags
+// to is a RFC 3339 time and defaults to now, from is a RFC 3339 time or a duration before to
+func parseTimeRange(from string, to string) (time.Time, time.Time, error) {
+	end := time.Now()
+	if to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -to %q, expected a RFC 3339 time", to)
+		}
+		end = t
+	}
+
+	start := end.Add(-defaultRange)
+	if from != "" {
+		if d, err := time.ParseDuration(from); err == nil {
+			start = end.Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, from); err == nil {
+			start = t
+		} else {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -from %q, expected a RFC 3339 time or a duration", from)
+		}
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("-from should be before -to")
+	}
+	return start, end, nil
+}
+
+// configURLs returns url if it is set, or the websites of the config that are not paused
+func configURLs(cfg config.Config, url string) []string {
+	if url != "" {
+		return []string{url}
+	}
+	urls := make([]string, 0, len(cfg.Websites))
+	for _, ws := range cfg.Websites {
+		if !ws.Paused {
+			urls = append(urls, ws.URL)
+		}
+	}
+	return urls
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
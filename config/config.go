@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/api"
+	"github.com/ayoubed/datadog-home-project/dashboard"
+	"github.com/ayoubed/datadog-home-project/database"
+	"github.com/ayoubed/datadog-home-project/maintenance"
+	"github.com/ayoubed/datadog-home-project/metrics"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/statuspage"
+	"github.com/ayoubed/datadog-home-project/web"
+)
+
+// Config struct containing websites config(url, check interval), database data(host, dbaname, username, password)
+type Config struct {
+	Websites    []monitor.Website    `json:"websites"`
+	Database    database.Type        `json:"database"`
+	Dashboard   []dashboard.View     `json:"dashboard"`
+	Alert       alerting.AlertConfig `json:"alerting"`
+	Maintenance maintenance.Config   `json:"maintenance"`
+	API         api.Config           `json:"api"`
+	Web         web.Config           `json:"web"`
+	StatusPage  statuspage.Config    `json:"statusPage"`
+	Metrics     metrics.Config       `json:"metrics"`
+
+	// files are the files the config was loaded from
+	files []string
+}
+
+// Load reads and validates a config file, in JSON, YAML or TOML depending on its extension,
+// along with the files it includes
+func Load(filepath string) (Config, error) {
+	raw, files, err := loadFiles(filepath)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config, err := fromRaw(raw)
+	if err != nil {
+		return Config{}, err
+	}
+	config.files = files
+
+	if err := Validate(config); err != nil {
+		return Config{}, err
+	}
+
+	return config, nil
+}
+
+// Parse decodes a JSON config and fills in the defaults
+// ${VAR} and ${VAR:-default} are replaced with environment variables and "file:path" values with the content of the file,
+// unknown fields are rejected, durations can be given in seconds or as strings like "30s" or "2m"
+func Parse(content []byte) (Config, error) {
+	raw, err := decode(".json", content)
+	if err != nil {
+		return Config{}, err
+	}
+	return fromRaw(raw)
+}
+
+func fromRaw(raw map[string]interface{}) (Config, error) {
+	if _, err := interpolate(raw, "", ""); err != nil {
+		return Config{}, err
+	}
+	if err := normalizeDurations(raw, ""); err != nil {
+		return Config{}, err
+	}
+	if _, err := conform(raw, reflect.TypeOf(Config{}), ""); err != nil {
+		return Config{}, err
+	}
+
+	normalized, err := json.Marshal(raw)
+	if err != nil {
+		return Config{}, err
+	}
+	var config Config
+	if err := json.Unmarshal(normalized, &config); err != nil {
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			return Config{}, fmt.Errorf("%v: expected a value of type %v, got a %v", indexPattern.ReplaceAllString(typeErr.Field, "[$1]"), typeErr.Type, typeErr.Value)
+		}
+		return Config{}, err
+	}
+	return ApplyDefaults(applyRawDefaults(config, raw)), nil
+}
+
+// Files returns the files the config was loaded from, and the directories of its include patterns
+func (c Config) Files() []string {
+	return c.files
+}
+
+// ModTime returns the last time a file of the config changed, or a file was added or removed from an included directory
+func (c Config) ModTime() time.Time {
+	var latest time.Time
+	for _, file := range c.files {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+// Persistable tells if the config can be written back to its file: it is a single JSON file
+func (c Config) Persistable() bool {
+	return len(c.files) == 1 && strings.ToLower(path.Ext(c.files[0])) == ".json"
+}
+
+// indexPattern matches the slice indexes in the field paths of encoding/json, like the 0 of websites.0.url
+var indexPattern = regexp.MustCompile(`\.(\d+)`)
+
+func lineOf(content []byte, offset int64) int {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	return bytes.Count(content[:offset], []byte("\n")) + 1
+}
+
+// Validate checks that every part of the config can be applied
+func Validate(config Config) error {
+	seen := make(map[string]bool)
+	// slowest is the longest check interval of the websites that are not paused
+	slowest := monitor.Website{}
+	for i, ws := range config.Websites {
+		if ws.URL == "" {
+			return fmt.Errorf("websites[%d] has no url", i)
+		}
+		if err := validateURL(ws.URL); err != nil {
+			return fmt.Errorf("website %v has an invalid url: %v", ws.URL, err)
+		}
+		if seen[ws.URL] {
+			return fmt.Errorf("website %v is declared twice", ws.URL)
+		}
+		seen[ws.URL] = true
+		if ws.CheckInterval <= 0 {
+			return fmt.Errorf("website %v should have a positive checkInterval", ws.URL)
+		}
+		if ws.ExpectedStatus != 0 && (ws.ExpectedStatus < 100 || ws.ExpectedStatus > 599) {
+			return fmt.Errorf("website %v has an invalid expectedStatus %d", ws.URL, ws.ExpectedStatus)
+		}
+		if !ws.Paused && ws.CheckInterval > slowest.CheckInterval {
+			slowest = ws
+		}
+	}
+
+	for _, view := range config.Dashboard {
+		if view.UpdateInterval <= 0 || view.TimeFrame <= 0 {
+			return fmt.Errorf("dashboard view %v should have a positive updateInterval and timeFrame", view.TimeFrame)
+		}
+		if view.TimeFrame < int64(slowest.CheckInterval) {
+			return fmt.Errorf("dashboard view %v has a timeFrame shorter than the checkInterval of %v (%ds), it would have no data to show", view.TimeFrame, slowest.URL, slowest.CheckInterval)
+		}
+	}
+
+	if err := alerting.Validate(config.Websites, config.Alert); err != nil {
+		return err
+	}
+	if err := maintenance.Validate(config.Maintenance); err != nil {
+		return err
+	}
+	if config.Database.InfluxDb.Port <= 0 || config.Database.InfluxDb.Port > 65535 {
+		return fmt.Errorf("database port %d is out of range", config.Database.InfluxDb.Port)
+	}
+	if config.StatusPage.Interval < 0 || config.StatusPage.Days < 0 {
+		return fmt.Errorf("the status page should have a positive interval and number of days")
+	}
+	return nil
+}
+
+// validateURL checks that a website url is an absolute http or https url
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("the scheme should be http or https")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("no host")
+	}
+	return nil
+}
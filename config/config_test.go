@@ -0,0 +1,222 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`{
+		"websites": [{"url": "https://a.com", "checkInterval": "30s"}, {"url": "https://b.com"}],
+		"alerting": {"availabilityInterval": "2m", "flapWindow": 600},
+		"maintenance": {"windows": [{"url": ".*", "schedule": "0 3 * * *", "duration": "1h30m"}]}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Websites[0].CheckInterval != 30 || cfg.Alert.AvailabilityInterval != 120 || cfg.Alert.FlapWindow != 600 || cfg.Maintenance.Windows[0].Duration != 5400 {
+		t.Errorf("Got %+v, want the durations in seconds", cfg)
+	}
+	if cfg.Websites[1].CheckInterval != defaultCheckInterval || len(cfg.Dashboard) != len(defaultViews) || cfg.Alert.AvailabilityThreshold != defaultAvailabilityThreshold {
+		t.Errorf("Got %+v, want the defaults to be filled in", cfg)
+	}
+	if err := Validate(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// a zero threshold only alerts when every check fails, it's not replaced with the default
+	cfg, err = Parse([]byte(`{"alerting": {"AvailabilityThreshold": 0}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Alert.AvailabilityThreshold != 0 {
+		t.Errorf("Got a threshold of %v, want the configured 0", cfg.Alert.AvailabilityThreshold)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`{"websites": [{"url": "https://a.com", "checkInterva": 5}]}`, `websites[0]: unknown field "checkInterva"`},
+		{`{"websites": [{"url": "https://a.com", "checkInterval": "5x"}]}`, `websites[0].checkInterval: invalid duration "5x"`},
+		{`{"websites": [{"url": "https://a.com", "checkInterval": "1500ms"}]}`, `not a whole number of seconds`},
+		{"{\n\"websites\": [\n}", `line 3`},
+	}
+	for _, test := range tests {
+		if _, err := Parse([]byte(test.content)); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Got %v for %v, want an error containing %q", err, test.content, test.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`{"websites": [{"url": "a.com"}]}`, "invalid url"},
+		{`{"websites": [{"url": "https://a.com"}, {"url": "https://a.com"}]}`, "declared twice"},
+		{`{"websites": [{"url": "https://a.com", "checkInterval": -1}]}`, "positive checkInterval"},
+		{`{"websites": [{"url": "https://a.com", "expectedStatus": 42}]}`, "invalid expectedStatus"},
+		{`{"websites": [{"url": "https://a.com", "checkInterval": "2m"}], "dashboard": [{"updateInterval": 10, "timeFrame": 60}]}`, "shorter than the checkInterval"},
+		{`{"alerting": {"availabilityThreshold": 1.5}}`, "between 0 and 1"},
+	}
+	for _, test := range tests {
+		cfg, err := Parse([]byte(test.content))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Got %v for %v, want an error containing %q", err, test.content, test.want)
+		}
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, content string) {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	write("config.yaml", `
+include: conf.d/*
+alerting:
+  availabilityThreshold: 0.9
+websites:
+  - url: https://a.com
+    checkInterval: 10s
+`)
+	write("conf.d/team-b.toml", `
+[[websites]]
+url = "https://b.com"
+checkInterval = "1m"
+`)
+	write("conf.d/team-c.json", `{"websites": [{"url": "https://c.com"}], "alerting": {"availabilityThreshold": 0.9}}`)
+
+	cfg, err := Load(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Websites) != 3 || cfg.Websites[0].CheckInterval != 10 || cfg.Websites[1].CheckInterval != 60 || cfg.Websites[2].URL != "https://c.com" {
+		t.Errorf("Got %+v, want the websites of the 3 files", cfg.Websites)
+	}
+	if cfg.Persistable() {
+		t.Errorf("a config made of several files should not be persistable")
+	}
+
+	// a website declared in two files
+	write("conf.d/team-d.json", `{"websites": [{"url": "https://b.com"}]}`)
+	if _, err := Load(filepath.Join(dir, "config.yaml")); err == nil || !strings.Contains(err.Error(), "team-b.toml and") {
+		t.Errorf("Got %v, want a conflict naming both files", err)
+	}
+
+	// a setting with different values
+	write("conf.d/team-d.json", `{"alerting": {"availabilityThreshold": 0.5}}`)
+	if _, err := Load(filepath.Join(dir, "config.yaml")); err == nil || !strings.Contains(err.Error(), "alerting.availabilityThreshold is set in both") {
+		t.Errorf("Got %v, want a conflict on the threshold", err)
+	}
+}
+
+func TestInterpolation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	passwordFile := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(passwordFile, []byte("s3cr3t-from-file\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	os.Setenv("MONITOR_TEST_HOST", "influx.internal")
+	os.Setenv("MONITOR_TEST_TOKEN", "hook-token-123")
+	defer os.Unsetenv("MONITOR_TEST_HOST")
+	defer os.Unsetenv("MONITOR_TEST_TOKEN")
+
+	cfg, err := Parse([]byte(`{
+		"database": {"influxDb": {"host": "${MONITOR_TEST_HOST}", "port": "${MONITOR_TEST_PORT:-8087}", "password": "file:` + passwordFile + `"}},
+		"alerting": {"notifiers": [{"name": "hook", "type": "webhook", "url": "https://hooks.example.com/${MONITOR_TEST_TOKEN}?literal=$${X}"}]}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db := cfg.Database.InfluxDb
+	if db.Host != "influx.internal" || db.Password != "s3cr3t-from-file" || cfg.Alert.Notifiers[0].URL != "https://hooks.example.com/hook-token-123?literal=${X}" {
+		t.Errorf("Got %+v and %+v, want the variables and the file to be resolved", db, cfg.Alert.Notifiers[0])
+	}
+	if got := secret.Redact("connecting with s3cr3t-from-file to https://hooks.example.com/hook-token-123 on influx.internal"); got != "connecting with **** to https://hooks.example.com/**** on influx.internal" {
+		t.Errorf("Got %q, want the password and the token to be redacted", got)
+	}
+
+	if _, err := Parse([]byte(`{"database": {"influxDb": {"username": "admin", "password": "hunter2-literal"}}}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := secret.Redact("login admin with hunter2-literal"); got != "login admin with ****" {
+		t.Errorf("Got %q, want the literal password to be redacted", got)
+	}
+
+	if _, err := Parse([]byte(`{"database": {"influxDb": {"password": "${MONITOR_TEST_MISSING}"}}}`)); err == nil || !strings.Contains(err.Error(), "database.influxDb.password: environment variable MONITOR_TEST_MISSING is not set") {
+		t.Errorf("Got %v, want an error for the missing variable", err)
+	}
+}
+
+func TestPatchWebsites(t *testing.T) {
+	os.Setenv("MONITOR_TEST_SITE_TOKEN", "site-token-456")
+	defer os.Unsetenv("MONITOR_TEST_SITE_TOKEN")
+
+	content := []byte(`{
+		"websites": [
+			{"url": "https://example.com/?token=${MONITOR_TEST_SITE_TOKEN}", "checkInterval": "30s"},
+			{"url": "https://blog.example.com", "checkInterval": 10},
+			{"url": "https://api.example.com", "checkInterval": "1m", "tags": {"team": "${MONITOR_TEST_TEAM:-core}"}}
+		],
+		"database": {"influxDb": {"password": "${MONITOR_TEST_PASSWORD}"}},
+		"alerting": {"availabilityThreshold": 0.9}
+	}`)
+	websites := []monitor.Website{
+		{URL: "https://example.com/?token=site-token-456", CheckInterval: 30, Paused: true},
+		{URL: "https://api.example.com", CheckInterval: 60, Tags: map[string]string{"team": "core"}},
+		{URL: "https://new.example.com/?a=1&b=2", CheckInterval: 5, ExpectedStatus: 204},
+	}
+
+	res, err := PatchWebsites(content, websites)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	patched := string(res)
+	for _, want := range []string{
+		`"url": "https://example.com/?token=${MONITOR_TEST_SITE_TOKEN}"`,
+		`"checkInterval": "30s"`,
+		`"paused": true`,
+		`"team": "${MONITOR_TEST_TEAM:-core}"`,
+		`"url": "https://new.example.com/?a=1&b=2"`,
+		`"password": "${MONITOR_TEST_PASSWORD}"`,
+	} {
+		if !strings.Contains(patched, want) {
+			t.Errorf("Got\n%v\nwant it to contain %v", patched, want)
+		}
+	}
+	if strings.Contains(patched, "site-token-456") || strings.Contains(patched, "blog.example.com") {
+		t.Errorf("Got\n%v\nwant the references kept and the removed website left out", patched)
+	}
+	if w, d, a := strings.Index(patched, `"websites"`), strings.Index(patched, `"database"`), strings.Index(patched, `"alerting"`); w > d || d > a {
+		t.Errorf("Got\n%v\nwant the keys in their original order", patched)
+	}
+}
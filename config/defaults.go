@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/ayoubed/datadog-home-project/dashboard"
+	"github.com/ayoubed/datadog-home-project/monitor"
+)
+
+// Default values of the settings left out of the config file, durations are in seconds
+const (
+	defaultCheckInterval         = 5
+	defaultAvailabilityInterval  = 120
+	defaultAvailabilityThreshold = 0.8
+	defaultAlertCheckInterval    = 5
+	defaultDatabaseHost          = "localhost"
+	defaultDatabasePort          = 8086
+)
+
+// defaultViews are the dashboard views used when the config has none:
+// the stats of the last 10 minutes every 10 seconds, and of the last hour every minute
+var defaultViews = []dashboard.View{{UpdateInterval: 10, TimeFrame: 600}, {UpdateInterval: 60, TimeFrame: 3600}}
+
+// ApplyDefaults fills the settings left out of a config with their default value
+// the availability threshold can be set to zero, so its default is applied when reading the config, see applyRawDefaults
+func ApplyDefaults(config Config) Config {
+	websites := make([]monitor.Website, len(config.Websites))
+	copy(websites, config.Websites)
+	for i := range websites {
+		if websites[i].CheckInterval == 0 {
+			websites[i].CheckInterval = defaultCheckInterval
+		}
+	}
+	config.Websites = websites
+
+	if len(config.Dashboard) == 0 {
+		config.Dashboard = append([]dashboard.View{}, defaultViews...)
+	}
+
+	if config.Alert.AvailabilityInterval == 0 {
+		config.Alert.AvailabilityInterval = defaultAvailabilityInterval
+	}
+	if config.Alert.CheckInterval == 0 {
+		config.Alert.CheckInterval = defaultAlertCheckInterval
+	}
+
+	if config.Database.InfluxDb.Host == "" {
+		config.Database.InfluxDb.Host = defaultDatabaseHost
+	}
+	if config.Database.InfluxDb.Port == 0 {
+		config.Database.InfluxDb.Port = defaultDatabasePort
+	}
+	return config
+}
+
+// applyRawDefaults fills the settings whose zero value is a valid setting, when they are left out of the raw config
+func applyRawDefaults(config Config, raw map[string]interface{}) Config {
+	if !isSet(raw, "alerting", "availabilityThreshold") {
+		config.Alert.AvailabilityThreshold = defaultAvailabilityThreshold
+	}
+	return config
+}
+
+// isSet tells if a raw config has a non-null value at the given path, keys are matched ignoring the case
+func isSet(raw map[string]interface{}, path ...string) bool {
+	var value interface{} = raw
+	for _, key := range path {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		value = nil
+		for k, v := range object {
+			if strings.EqualFold(k, key) {
+				value = v
+			}
+		}
+	}
+	return value != nil
+}
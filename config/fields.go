@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationKeys are the config keys holding a number of seconds
+// they also accept a duration string like "30s", "2m" or "1h30m"
+var durationKeys = map[string]bool{
+	"checkInterval":        true,
+	"updateInterval":       true,
+	"timeFrame":            true,
+	"availabilityInterval": true,
+	"minStateDuration":     true,
+	"flapWindow":           true,
+	"groupWait":            true,
+	"repeatInterval":       true,
+	"escalateAfter":        true,
+	"duration":             true,
+	"interval":             true,
+}
+
+// normalizeDurations replaces the duration strings of a decoded config with their number of seconds
+func normalizeDurations(v interface{}, path string) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			s, ok := value.(string)
+			if durationKeys[key] && ok {
+				if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+					v[key] = json.Number(s)
+					continue
+				}
+				d, err := time.ParseDuration(s)
+				if err != nil {
+					return fmt.Errorf("%v: invalid duration %q, expected a number of seconds or a duration like \"30s\" or \"2m\"", join(path, key), s)
+				}
+				if d%time.Second != 0 {
+					return fmt.Errorf("%v: %q is not a whole number of seconds", join(path, key), s)
+				}
+				v[key] = json.Number(fmt.Sprint(int64(d / time.Second)))
+				continue
+			}
+			if err := normalizeDurations(value, join(path, key)); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			if err := normalizeDurations(value, fmt.Sprintf("%v[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// conform checks a decoded config against the type it is decoded into, and returns the value to decode
+// it reports the first key that doesn't match a field of t, keys being matched like encoding/json does, ignoring the case
+// strings are turned into numbers or booleans where t expects one, like the values of environment variables
+func conform(v interface{}, t reflect.Type, path string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return v, nil
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Map:
+			for key, value := range v {
+				conformed, err := conform(value, t.Elem(), join(path, key))
+				if err != nil {
+					return nil, err
+				}
+				v[key] = conformed
+			}
+		case reflect.Struct:
+			fields := structFields(t)
+			for key, value := range v {
+				field, ok := fields[strings.ToLower(key)]
+				if !ok {
+					if path == "" {
+						return nil, fmt.Errorf("unknown field %q", key)
+					}
+					return nil, fmt.Errorf("%v: unknown field %q", path, key)
+				}
+				conformed, err := conform(value, field.Type, join(path, key))
+				if err != nil {
+					return nil, err
+				}
+				v[key] = conformed
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, value := range v {
+				conformed, err := conform(value, t.Elem(), fmt.Sprintf("%v[%d]", path, i))
+				if err != nil {
+					return nil, err
+				}
+				v[i] = conformed
+			}
+		}
+	case string:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("%v: expected a number, got %q", path, v)
+			}
+			return json.Number(v), nil
+		case reflect.Bool:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%v: expected a boolean, got %q", path, v)
+			}
+			return b, nil
+		}
+	}
+	return v, nil
+}
+
+// structFields maps the lowercased JSON names of the fields of a struct to the fields
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			for name, embedded := range structFields(field.Type) {
+				fields[name] = embedded
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field
+	}
+	return fields
+}
+
+func join(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
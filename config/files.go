@@ -0,0 +1,248 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey lists glob patterns of other config files to merge into the one declaring it,
+// relative to its directory, like "conf.d/*.yaml"
+const includeKey = "include"
+
+// identityKeys are the lists whose items must be unique across the files, with the key identifying an item
+var identityKeys = map[string]string{
+	"websites":           "url",
+	"alerting.notifiers": "name",
+}
+
+// merger merges config files into a single raw config, detecting the settings declared twice
+type merger struct {
+	// origins maps each setting and list item to the file declaring it
+	origins map[string]string
+	// files are the loaded files and the directories of the include patterns, the ones to watch for changes
+	files []string
+	seen  map[string]bool
+}
+
+// loadFiles reads a config file and the files it includes, and merges them
+func loadFiles(path string) (map[string]interface{}, []string, error) {
+	m := &merger{origins: make(map[string]string), seen: make(map[string]bool)}
+	raw := make(map[string]interface{})
+	if err := m.load(raw, path); err != nil {
+		return nil, nil, err
+	}
+	return raw, m.files, nil
+}
+
+func (m *merger) load(dst map[string]interface{}, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if m.seen[abs] {
+		return nil
+	}
+	m.seen[abs] = true
+	m.files = append(m.files, path)
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	src, err := decode(path, content)
+	if err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+
+	patterns, err := includes(src[includeKey])
+	if err != nil {
+		return fmt.Errorf("%v: %v", path, err)
+	}
+	delete(src, includeKey)
+	if err := m.merge(dst, src, "", path); err != nil {
+		return err
+	}
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("%v: invalid include pattern %q: %v", path, pattern, err)
+		}
+		m.files = append(m.files, filepath.Dir(pattern))
+		sort.Strings(matches)
+		for _, match := range matches {
+			if err := m.load(dst, match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge adds the settings of src to dst: lists are concatenated and objects merged key by key
+// a value set in two files is a conflict, unless both files agree on it
+func (m *merger) merge(dst map[string]interface{}, src map[string]interface{}, path string, file string) error {
+	keys := make([]string, 0, len(src))
+	for key := range src {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := src[key]
+		keyPath := join(path, key)
+		existing, ok := dst[key]
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			existingMap, isMap := existing.(map[string]interface{})
+			if ok && !isMap {
+				return m.conflict(keyPath, file)
+			}
+			if !ok {
+				existingMap = make(map[string]interface{})
+				dst[key] = existingMap
+			}
+			if err := m.merge(existingMap, value, keyPath, file); err != nil {
+				return err
+			}
+		case []interface{}:
+			existingList, isList := existing.([]interface{})
+			if ok && !isList {
+				return m.conflict(keyPath, file)
+			}
+			if err := m.identify(keyPath, value, file); err != nil {
+				return err
+			}
+			dst[key] = append(existingList, value...)
+		default:
+			if ok && fmt.Sprint(existing) != fmt.Sprint(value) {
+				return m.conflict(keyPath, file)
+			}
+			if !ok {
+				m.origins[keyPath] = file
+			}
+			dst[key] = value
+		}
+	}
+	return nil
+}
+
+// identify records the origin of the items of the lists that need unique items
+func (m *merger) identify(path string, items []interface{}, file string) error {
+	idKey, ok := identityKeys[path]
+	if !ok {
+		return nil
+	}
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id := fmt.Sprintf("%v[%v=%v]", path, idKey, fields[idKey])
+		if origin, ok := m.origins[id]; ok {
+			return fmt.Errorf("%v is declared in both %v and %v", id, origin, file)
+		}
+		m.origins[id] = file
+	}
+	return nil
+}
+
+func (m *merger) conflict(path string, file string) error {
+	if origin, ok := m.origins[path]; ok && origin != file {
+		return fmt.Errorf("%v is set in both %v and %v", path, origin, file)
+	}
+	return fmt.Errorf("%v is set twice with different values in %v", path, file)
+}
+
+// decode reads a JSON, YAML or TOML config depending on the extension of the file
+func decode(path string, content []byte) (map[string]interface{}, error) {
+	var raw interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %v", err)
+		}
+	case ".toml":
+		var table map[string]interface{}
+		if _, err := toml.Decode(string(content), &table); err != nil {
+			return nil, fmt.Errorf("invalid TOML: %v", err)
+		}
+		raw = normalizeTOML(table)
+	default:
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			if syntaxErr, ok := err.(*json.SyntaxError); ok {
+				return nil, fmt.Errorf("invalid JSON at line %d: %v", lineOf(content, syntaxErr.Offset), err)
+			}
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	}
+
+	if raw == nil {
+		// an empty file
+		return make(map[string]interface{}), nil
+	}
+	table, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the config should be an object, not a %v", reflect.TypeOf(raw))
+	}
+	return table, nil
+}
+
+// normalizeTOML turns the arrays of tables of a TOML document into plain lists
+func normalizeTOML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalizeTOML(value)
+		}
+		return v
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, normalizeTOML(item))
+		}
+		return list
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeTOML(item)
+		}
+		return v
+	}
+	return v
+}
+
+// includes reads the include patterns of a file, a single pattern or a list of them
+func includes(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		patterns := make([]string, 0, len(v))
+		for _, item := range v {
+			pattern, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include should list glob patterns")
+			}
+			patterns = append(patterns, pattern)
+		}
+		return patterns, nil
+	}
+	return nil, fmt.Errorf("include should be a glob pattern or a list of them")
+}
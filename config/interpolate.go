@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ayoubed/datadog-home-project/secret"
+)
+
+// filePrefix marks the values read from a file, like the secrets mounted by Docker or Kubernetes
+const filePrefix = "file:"
+
+// variablePattern matches ${NAME} and ${NAME:-default}, $${ is a literal ${
+var variablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// secretPattern matches the names of the keys and variables holding secrets
+var secretPattern = regexp.MustCompile(`(?i)password|secret|token|key`)
+
+// interpolate replaces the environment variables and the file references in the string values of a raw config
+// the values coming from files, or put in a key or read from a variable whose name looks like a secret, are registered as secrets,
+// literal values included
+func interpolate(v interface{}, path string, key string) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			resolved, err := interpolate(value, join(path, k), k)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = resolved
+		}
+		return v, nil
+	case []interface{}:
+		for i, value := range v {
+			resolved, err := interpolate(value, fmt.Sprintf("%v[%d]", path, i), key)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = resolved
+		}
+		return v, nil
+	case string:
+		return interpolateString(v, path, key)
+	}
+	return v, nil
+}
+
+func interpolateString(s string, path string, key string) (string, error) {
+	if strings.HasPrefix(s, filePrefix) {
+		name := strings.TrimPrefix(s, filePrefix)
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return "", fmt.Errorf("%v: error reading %v: %v", path, name, err)
+		}
+		value := strings.TrimRight(string(content), "\r\n")
+		secret.Register(value)
+		return value, nil
+	}
+
+	var err error
+	res := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		groups := variablePattern.FindStringSubmatch(match)
+		name, hasDefault, fallback := groups[1], groups[2] != "", groups[3]
+
+		value, ok := os.LookupEnv(name)
+		if !ok || (value == "" && hasDefault) {
+			if !hasDefault {
+				if err == nil {
+					err = fmt.Errorf("%v: environment variable %v is not set", path, name)
+				}
+				return match
+			}
+			value = fallback
+		}
+		if secretPattern.MatchString(name) || secretPattern.MatchString(key) {
+			secret.Register(value)
+		}
+		return value
+	})
+	if err == nil && secretPattern.MatchString(key) {
+		secret.Register(res)
+	}
+	return res, err
+}
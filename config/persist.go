@@ -0,0 +1,204 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ayoubed/datadog-home-project/monitor"
+)
+
+// field is a key of a JSON object with its raw value, objects are kept as lists of fields to keep the order of their keys
+type field struct {
+	key   string
+	value json.RawMessage
+}
+
+// PatchWebsites replaces the websites of a JSON config file with the given ones, and returns the new content of the file
+// the entries of the websites that didn't change are kept as they are, and only the changed keys of the other ones are replaced,
+// so environment variables and file references are not replaced with their value; the order of the keys is kept
+func PatchWebsites(content []byte, websites []monitor.Website) ([]byte, error) {
+	fields, err := decodeObject(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	var entries []json.RawMessage
+	index := -1
+	for i, f := range fields {
+		if f.key == "websites" {
+			index = i
+			if err := json.Unmarshal(f.value, &entries); err != nil {
+				return nil, fmt.Errorf("websites: %v", err)
+			}
+		}
+	}
+
+	// the entries of the file, by the URL they resolve to
+	existing := make(map[string]json.RawMessage)
+	resolved := make(map[string]monitor.Website)
+	for i, entry := range entries {
+		var item interface{}
+		if err := json.Unmarshal(entry, &item); err != nil {
+			return nil, err
+		}
+		cfg, err := fromRaw(map[string]interface{}{"websites": []interface{}{item}})
+		if err != nil {
+			return nil, fmt.Errorf("websites[%d]: %v", i, err)
+		}
+		existing[cfg.Websites[0].URL] = entry
+		resolved[cfg.Websites[0].URL] = cfg.Websites[0]
+	}
+
+	patched := make([]json.RawMessage, 0, len(websites))
+	for _, ws := range websites {
+		entry, ok := existing[ws.URL]
+		var err error
+		switch {
+		case !ok:
+			entry, err = marshal(ws)
+		case !reflect.DeepEqual(resolved[ws.URL], ws):
+			entry, err = patchWebsite(entry, resolved[ws.URL], ws)
+		}
+		if err != nil {
+			return nil, err
+		}
+		patched = append(patched, entry)
+	}
+
+	value, err := marshal(patched)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 {
+		fields = append(fields, field{key: "websites"})
+		index = len(fields) - 1
+	}
+	fields[index].value = value
+	return encodeObject(fields)
+}
+
+// patchWebsite replaces the keys of a website entry whose resolved value changed from old to ws
+func patchWebsite(entry json.RawMessage, old monitor.Website, ws monitor.Website) (json.RawMessage, error) {
+	fields, err := decodeObject(entry)
+	if err != nil {
+		return nil, err
+	}
+	oldFields, err := websiteFields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := websiteFields(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	// the keys of ws, then the ones of old left out of ws because they're empty
+	keys := make([]string, 0, len(newFields)+len(oldFields))
+	for _, f := range newFields {
+		keys = append(keys, f.key)
+	}
+	for _, f := range oldFields {
+		if _, ok := lookup(newFields, f.key); !ok {
+			keys = append(keys, f.key)
+		}
+	}
+	for _, key := range keys {
+		value, ok := lookup(newFields, key)
+		if oldValue, _ := lookup(oldFields, key); bytes.Equal(value, oldValue) {
+			continue
+		}
+		// keys are matched ignoring the case, like when the config is read
+		i := 0
+		for i < len(fields) && !strings.EqualFold(fields[i].key, key) {
+			i++
+		}
+		switch {
+		case !ok && i < len(fields):
+			fields = append(fields[:i], fields[i+1:]...)
+		case ok && i < len(fields):
+			fields[i].value = value
+		case ok:
+			fields = append(fields, field{key: key, value: value})
+		}
+	}
+	return encodeObject(fields)
+}
+
+// websiteFields returns the keys of the JSON encoding of a website, in order
+func websiteFields(ws monitor.Website) ([]field, error) {
+	content, err := marshal(ws)
+	if err != nil {
+		return nil, err
+	}
+	return decodeObject(content)
+}
+
+// lookup returns the value of a key of an object
+func lookup(fields []field, key string) (json.RawMessage, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// decodeObject reads the keys of a JSON object in order
+func decodeObject(content []byte) ([]field, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	if t, err := decoder.Token(); err != nil || t != json.Delim('{') {
+		return nil, fmt.Errorf("expected an object")
+	}
+	fields := make([]field, 0)
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{key: t.(string), value: value})
+	}
+	return fields, nil
+}
+
+// encodeObject writes a JSON object with the keys in order, indented with two spaces
+func encodeObject(fields []field) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := marshal(f.key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(f.value)
+	}
+	b.WriteString("}")
+
+	var res bytes.Buffer
+	if err := json.Indent(&res, b.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	return res.Bytes(), nil
+}
+
+// marshal encodes a value without escaping the HTML characters, like the & of the query strings of the URLs
+func marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/config"
+	"github.com/ayoubed/datadog-home-project/dashboard"
+	"github.com/ayoubed/datadog-home-project/eventlog"
+	"github.com/ayoubed/datadog-home-project/maintenance"
+	"github.com/ayoubed/datadog-home-project/metrics"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
+	"github.com/fatih/color"
+)
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 2 * time.Second
+
+// errNotPersistable is returned when asked to write changes to a config that spans several files or isn't JSON
+var errNotPersistable = errors.New("changes can only be written back to a config made of a single JSON file")
+
+var red *color.Color = color.New(color.FgRed)
+var yellow *color.Color = color.New(color.FgYellow)
+
+// controller owns the running config, it applies a new config on SIGHUP, when the config file changes
+// or when the websites are changed through the management API
+// an invalid config is rejected and the current one keeps running
+type controller struct {
+	ctx          context.Context
+	path         string
+	manager      *monitor.Manager
+	viewc        chan []dashboard.View
+	alertReloadc chan alerting.Reload
+	alertc       chan string
+
+	mu      sync.Mutex
+	current config.Config
+	modTime time.Time
+}
+
+func (c *controller) run() error {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	c.mu.Lock()
+	c.modTime = c.fileModTime()
+	c.mu.Unlock()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-sighup:
+			c.reload(true)
+		case <-ticker.C:
+			c.reload(false)
+		}
+	}
+}
+
+// fileModTime returns the last time the files of the config changed, the caller must hold c.mu
+func (c *controller) fileModTime() time.Time {
+	return c.current.ModTime()
+}
+
+// reload applies the config file, if it changed since the last time it was read or if force is set
+func (c *controller) reload(force bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTime := c.fileModTime()
+	if !force && modTime.Equal(c.modTime) {
+		return
+	}
+	c.modTime = modTime
+
+	cfg, err := config.Load(c.path)
+	databaseChanged := err == nil && !reflect.DeepEqual(cfg.Database, c.current.Database)
+	if err == nil {
+		err = c.apply(cfg)
+	}
+	if err != nil {
+		c.report(red, fmt.Sprintf("Config reload rejected, keeping the current config: %v, time = %s\n", err, time.Now().Format(time.RFC1123)))
+		return
+	}
+
+	// the reloaded config may include other files
+	c.modTime = c.fileModTime()
+
+	message := "Config reloaded"
+	if databaseChanged {
+		message = "Config reloaded, database changes need a restart"
+	}
+	c.report(yellow, fmt.Sprintf("%v, time = %s\n", message, time.Now().Format(time.RFC1123)))
+}
+
+// apply validates a config and reconfigures what changed, the caller must hold c.mu
+// the database settings are kept, they need a restart
+func (c *controller) apply(cfg config.Config) error {
+	if err := config.Validate(cfg); err != nil {
+		return err
+	}
+	if err := maintenance.Set(cfg.Maintenance); err != nil {
+		return err
+	}
+	c.manager.Apply(cfg.Websites)
+	metrics.Retain(c.manager.URLs())
+
+	if !reflect.DeepEqual(cfg.Dashboard, c.current.Dashboard) {
+		select {
+		case c.viewc <- cfg.Dashboard:
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+	}
+	if !reflect.DeepEqual(cfg.Websites, c.current.Websites) || !reflect.DeepEqual(cfg.Alert, c.current.Alert) {
+		select {
+		case c.alertReloadc <- alerting.Reload{Websites: cfg.Websites, Config: cfg.Alert}:
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+	}
+
+	cfg.Database = c.current.Database
+	c.current = cfg
+	return nil
+}
+
+// report shows a message in the alerts pane of the dashboard and writes it to the event log
+func (c *controller) report(messageColor *color.Color, message string) {
+	if err := eventlog.Message(strings.TrimSpace(message)); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", secret.Redact(err.Error()))
+	}
+	select {
+	case c.alertc <- messageColor.Sprint(message):
+	case <-c.ctx.Done():
+	}
+}
+
+// Views returns the configured dashboard views
+func (c *controller) Views() []dashboard.View {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.current.Dashboard
+}
+
+// Websites returns the configured websites, paused ones included
+func (c *controller) Websites() []monitor.Website {
+	return c.manager.Websites()
+}
+
+// Add starts monitoring a new website
+func (c *controller) Add(website monitor.Website, persist bool) error {
+	return c.updateWebsites(persist, func(websites []monitor.Website) ([]monitor.Website, error) {
+		return append(websites, website), nil
+	})
+}
+
+// Update replaces the settings of a website
+func (c *controller) Update(website monitor.Website, persist bool) error {
+	return c.updateWebsite(website.URL, persist, func(ws *monitor.Website) {
+		*ws = website
+	})
+}
+
+// Remove stops monitoring a website
+func (c *controller) Remove(url string, persist bool) error {
+	return c.updateWebsites(persist, func(websites []monitor.Website) ([]monitor.Website, error) {
+		for i, ws := range websites {
+			if ws.URL == url {
+				return append(websites[:i:i], websites[i+1:]...), nil
+			}
+		}
+		return nil, monitor.ErrUnknownWebsite
+	})
+}
+
+// SetPaused pauses or resumes the checks of a website
+func (c *controller) SetPaused(url string, paused bool, persist bool) error {
+	return c.updateWebsite(url, persist, func(ws *monitor.Website) {
+		ws.Paused = paused
+	})
+}
+
+func (c *controller) updateWebsite(url string, persist bool, update func(ws *monitor.Website)) error {
+	return c.updateWebsites(persist, func(websites []monitor.Website) ([]monitor.Website, error) {
+		for i := range websites {
+			if websites[i].URL == url {
+				update(&websites[i])
+				return websites, nil
+			}
+		}
+		return nil, monitor.ErrUnknownWebsite
+	})
+}
+
+// updateWebsites applies a change to a copy of the websites list, and optionally writes the result to the config file first
+func (c *controller) updateWebsites(persist bool, update func([]monitor.Website) ([]monitor.Website, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if persist && !c.current.Persistable() {
+		return errNotPersistable
+	}
+
+	websites, err := update(append([]monitor.Website{}, c.current.Websites...))
+	if err != nil {
+		return err
+	}
+	cfg := c.current
+	cfg.Websites = websites
+	cfg = config.ApplyDefaults(cfg)
+
+	// the change is written before being applied, so a change that can't be written is not live either
+	if persist {
+		if err := config.Validate(cfg); err != nil {
+			return err
+		}
+		if err := c.persist(cfg.Websites); err != nil {
+			return err
+		}
+	}
+	return c.apply(cfg)
+}
+
+// persist writes websites to the config file, the caller must hold c.mu
+// only the changed website entries are rewritten, so environment variables and file references are not replaced with their value
+func (c *controller) persist(websites []monitor.Website) error {
+	content, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return fmt.Errorf("error reading the config file: %v", err)
+	}
+	content, err = config.PatchWebsites(content, websites)
+	if err != nil {
+		return fmt.Errorf("error updating the config file: %v", err)
+	}
+	if err := ioutil.WriteFile(c.path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing the config file: %v", err)
+	}
+	// we don't want the file watcher to reload our own changes
+	c.modTime = c.fileModTime()
+	return nil
+}
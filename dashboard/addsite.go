@@ -0,0 +1,181 @@
+package dashboard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+)
+
+// AddFunc starts monitoring a website, and writes it to the config file when persist is set
+type AddFunc func(website monitor.Website, persist bool) error
+
+// formField is an input line of the form adding a website
+type formField struct {
+	name  string
+	title string
+	value string
+}
+
+var formFields = []formField{
+	{"add-url", "URL", "https://"},
+	{"add-interval", "Check interval: 30s, 1m or a number of seconds", "5s"},
+	{"add-status", "Expected status code", "200"},
+	{"add-persist", "Write it to the config file: yes or no", "no"},
+}
+
+// addWebsite is set by Run, the form is disabled when it's nil
+var addWebsite AddFunc
+
+// isFormField tells if a view is an input line of the form
+func isFormField(v *gocui.View) bool {
+	return v != nil && strings.HasPrefix(v.Name(), "add-")
+}
+
+// formPosition returns the coordinates of the frame of the form, or of one of its fields
+func formPosition(name string, maxX, maxY int) (int, int, int, int) {
+	width := 80
+	if width > maxX-2 {
+		width = maxX - 2
+	}
+	x0, y0 := (maxX-width)/2, (maxY-len(formFields)*3-2)/2
+	if name == "add" {
+		return x0, y0, x0 + width, y0 + len(formFields)*3 + 1
+	}
+	for i, field := range formFields {
+		if field.name == name {
+			return x0 + 1, y0 + 1 + 3*i, x0 + width - 1, y0 + 3 + 3*i
+		}
+	}
+	return x0, y0, x0 + width, y0
+}
+
+// openForm opens the form adding a website
+func openForm(g *gocui.Gui) error {
+	if addWebsite == nil {
+		return nil
+	}
+	v, err := openOverlay(g, "add")
+	if err != nil {
+		return err
+	}
+	v.Title = " Add a website (Tab for the next field, Enter to add, Esc to cancel) "
+	for _, field := range formFields {
+		v, err := openOverlay(g, field.name)
+		if err != nil {
+			return err
+		}
+		v.Title = " " + field.title + " "
+		v.Editable = true
+		fmt.Fprint(v, field.value)
+		if err := v.SetCursor(len(field.value), 0); err != nil {
+			return err
+		}
+	}
+	g.Cursor = true
+	_, err = g.SetCurrentView(formFields[0].name)
+	return err
+}
+
+// nextField gives the keys to the field following v in the form
+func nextField(g *gocui.Gui, v *gocui.View) error {
+	for i, field := range formFields {
+		if field.name == v.Name() {
+			_, err := g.SetCurrentView(formFields[(i+1)%len(formFields)].name)
+			return err
+		}
+	}
+	return nil
+}
+
+// closeForm closes the form, without adding the website
+func closeForm(g *gocui.Gui) error {
+	g.Cursor = false
+	for _, field := range formFields {
+		if err := g.DeleteView(field.name); err != nil && err != gocui.ErrUnknownView {
+			return err
+		}
+	}
+	return closeOverlay(g, "add")
+}
+
+// submitForm adds the website of the form, the errors are shown in the title of the form which stays open
+func submitForm(g *gocui.Gui) error {
+	values := make([]string, len(formFields))
+	for i, field := range formFields {
+		v, err := g.View(field.name)
+		if err != nil {
+			return err
+		}
+		values[i] = v.Buffer()
+	}
+	frame, err := g.View("add")
+	if err != nil {
+		return err
+	}
+
+	website, persist, err := parseForm(values[0], values[1], values[2], values[3])
+	if err != nil {
+		frame.Title = " " + err.Error() + " "
+		return nil
+	}
+	frame.Title = " Adding " + secret.Redact(website.URL) + "... "
+
+	// applying the website waits for the monitors and the alert logic, don't block the main loop meanwhile
+	go func() {
+		err := addWebsite(website, persist)
+		g.Update(func(g *gocui.Gui) error {
+			if err != nil {
+				if frame, verr := g.View("add"); verr == nil {
+					frame.Title = secret.Redact(fmt.Sprintf(" Error adding %v: %v ", website.URL, err))
+				}
+				return nil
+			}
+			message := fmt.Sprintf("Website %v added, time = %s\n", website.URL, time.Now().Format(time.RFC1123))
+			if persist {
+				message = fmt.Sprintf("Website %v added and written to the config file, time = %s\n", website.URL, time.Now().Format(time.RFC1123))
+			}
+			addEntry(g, messageEntry(time.Now(), color.YellowString(message)))
+			return closeForm(g)
+		})
+	}()
+	return nil
+}
+
+// parseForm reads the fields of the form: the URL, the check interval, the expected status code and whether to persist the website
+func parseForm(url, interval, status, persist string) (monitor.Website, bool, error) {
+	website := monitor.Website{URL: strings.TrimSpace(url)}
+
+	interval = strings.TrimSpace(interval)
+	if seconds, err := strconv.Atoi(interval); err == nil {
+		website.CheckInterval = seconds
+	} else if d, err := time.ParseDuration(interval); err == nil && d%time.Second == 0 {
+		website.CheckInterval = int(d / time.Second)
+	} else {
+		return monitor.Website{}, false, fmt.Errorf("invalid check interval %q, expected a number of seconds or a duration like 30s", interval)
+	}
+
+	if status = strings.TrimSpace(status); status != "" {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return monitor.Website{}, false, fmt.Errorf("invalid status code %q", status)
+		}
+		// 200 is the default, it's left out of the config file
+		if code != 200 {
+			website.ExpectedStatus = code
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(persist)) {
+	case "yes", "y", "true":
+		return website, true, nil
+	case "no", "n", "false", "":
+		return website, false, nil
+	}
+	return monitor.Website{}, false, fmt.Errorf("invalid answer %q to writing the website to the config file, expected yes or no", persist)
+}
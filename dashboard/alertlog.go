@@ -0,0 +1,314 @@
+package dashboard
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/secret"
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+)
+
+// alertLogSize is the number of alerts and messages kept by the alerts pane, the oldest ones are dropped
+const alertLogSize = 1000
+
+// resolvedShown is the number of resolved incidents listed above the log
+const resolvedShown = 5
+
+// logEntry is a line of the alerts pane: an alert, or a message about the dashboard, the config or the notifications
+type logEntry struct {
+	time  time.Time
+	alert *alerting.Alert
+	// text is the message without colors, the filter searches it
+	text string
+	// display is the message as it is shown
+	display string
+}
+
+var (
+	// alertLog is the content of the alerts pane, oldest first
+	alertLog []logEntry
+	// alertFilter is applied to the alerts pane, see matchesAlertFilter
+	alertFilter string
+	alertsMu    sync.Mutex
+)
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// alertEntry makes a log entry of an alert, the secrets of its URL are redacted
+func alertEntry(alert alerting.Alert) logEntry {
+	message := secret.Redact(alerting.Format(alert))
+	return logEntry{time: alert.Time, alert: &alert, text: ansiPattern.ReplaceAllString(message, ""), display: message}
+}
+
+// messageEntry makes a log entry of a message received at t
+func messageEntry(t time.Time, message string) logEntry {
+	message = secret.Redact(message)
+	return logEntry{time: t, text: ansiPattern.ReplaceAllString(message, ""), display: message}
+}
+
+// severity returns the severity of the alert of the entry, messages are informational
+func (e logEntry) severity() string {
+	if e.alert == nil {
+		return alerting.SeverityInfo
+	}
+	return e.alert.Severity
+}
+
+// appendEntry adds an entry to a log, and drops the oldest entries past alertLogSize
+// an alert already in the log is skipped, the alerts pane reads them from the history and from a subscription
+func appendEntry(log []logEntry, e logEntry) []logEntry {
+	if logged(log, e) {
+		return log
+	}
+	log = append(log, e)
+	if len(log) > alertLogSize {
+		log = append(log[:0:0], log[len(log)-alertLogSize:]...)
+	}
+	return log
+}
+
+// logged tells if the alert of an entry is already in a log, the alerts of the history are stored to the millisecond
+func logged(log []logEntry, e logEntry) bool {
+	if e.alert == nil {
+		return false
+	}
+	for i := len(log) - 1; i >= 0 && !log[i].time.Before(e.time.Truncate(time.Millisecond)); i-- {
+		if a := log[i].alert; a != nil && a.Same(*e.alert) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesAlertFilter tells if an entry matches every space-separated term of the filter:
+// severity:critical matches the alerts of a severity, site:name the alerts of the websites whose URL contains name,
+// and other terms are searched in the message, ignoring the case
+func matchesAlertFilter(e logEntry, filter string) bool {
+	for _, term := range strings.Fields(filter) {
+		switch {
+		case strings.HasPrefix(term, "severity:"):
+			if e.severity() != strings.TrimPrefix(term, "severity:") {
+				return false
+			}
+		case strings.HasPrefix(term, "site:"):
+			if e.alert == nil || !strings.Contains(e.alert.URL, strings.TrimPrefix(term, "site:")) {
+				return false
+			}
+		default:
+			if !strings.Contains(strings.ToLower(e.text), strings.ToLower(term)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// visibleEntries returns the entries matching the filter, up to the time the dashboard shows
+func visibleEntries(log []logEntry, filter string, until time.Time) []logEntry {
+	res := make([]logEntry, 0, len(log))
+	for _, e := range log {
+		if !e.time.After(until) && matchesAlertFilter(e, filter) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// writeAlerts writes the ongoing incidents, the last resolved ones and the entries, newest first
+func writeAlerts(w io.Writer, entries []logEntry, now time.Time) {
+	header := color.New(color.FgYellow, color.Bold)
+
+	alerts := make([]alerting.Alert, 0, len(entries))
+	for _, e := range entries {
+		if e.alert != nil {
+			alerts = append(alerts, *e.alert)
+		}
+	}
+	active, resolved := make([]alerting.Incident, 0), make([]alerting.Incident, 0)
+	for _, incident := range alerting.Incidents(alerts) {
+		if incident.Ongoing() {
+			active = append(active, incident)
+		} else {
+			resolved = append(resolved, incident)
+		}
+	}
+
+	header.Fprintf(w, "Active incidents (%d)\n", len(active))
+	for _, incident := range active {
+		color.New(color.FgRed).Fprintf(w, "  %-40v down since %v, for %v\n", secret.Redact(incident.URL), incident.Start.Format("2006-01-02 15:04:05"), formatDuration(incident.Duration(now)))
+	}
+	if len(resolved) > 0 {
+		header.Fprintf(w, "Resolved incidents (%d, last %d)\n", len(resolved), resolvedShown)
+		for i := len(resolved) - 1; i >= 0 && i >= len(resolved)-resolvedShown; i-- {
+			incident := resolved[i]
+			fmt.Fprintf(w, "  %-40v down at %v for %v\n", secret.Redact(incident.URL), incident.Start.Format("2006-01-02 15:04:05"), formatDuration(incident.Duration(now)))
+		}
+	}
+
+	header.Fprintf(w, "\nLog (%d)\n", len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		fmt.Fprintf(w, "%v %v\n", formatSeverity(e.severity()), strings.TrimRight(e.display, "\n"))
+	}
+}
+
+// severityLabel is the label of a severity in the alerts pane and the plain output
+func severityLabel(severity string) string {
+	switch severity {
+	case alerting.SeverityCritical:
+		return "[CRIT]"
+	case alerting.SeverityWarning:
+		return "[WARN]"
+	}
+	return "[INFO]"
+}
+
+// formatSeverity is the colored label of a severity
+func formatSeverity(severity string) string {
+	switch severity {
+	case alerting.SeverityCritical:
+		return color.RedString(severityLabel(severity))
+	case alerting.SeverityWarning:
+		return color.YellowString(severityLabel(severity))
+	}
+	return color.CyanString(severityLabel(severity))
+}
+
+// formatDuration rounds a duration to the second
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// addEntry adds an entry to the alerts pane, and redraws it unless the dashboard is paused
+func addEntry(g *gocui.Gui, e logEntry) {
+	alertsMu.Lock()
+	alertLog = appendEntry(alertLog, e)
+	alertsMu.Unlock()
+	if !isPaused() {
+		drawAlerts(g)
+	}
+}
+
+// visibleAlerts returns the entries shown by the alerts pane and the time they're shown at
+func visibleAlerts() ([]logEntry, time.Time) {
+	now := currentOrigin()
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	return visibleEntries(alertLog, alertFilter, now), now
+}
+
+// drawAlerts redraws the alerts pane
+func drawAlerts(g *gocui.Gui) {
+	g.Update(func(g *gocui.Gui) error {
+		v, err := g.View("alerts")
+		if err != nil {
+			return err
+		}
+		entries, now := visibleAlerts()
+		v.Clear()
+		writeAlerts(v, entries, now)
+		return nil
+	})
+}
+
+// setAlertFilter applies a new filter to the alerts pane
+func setAlertFilter(g *gocui.Gui, f string) {
+	alertsMu.Lock()
+	alertFilter = strings.TrimSpace(f)
+	alertsMu.Unlock()
+	drawAlerts(g)
+}
+
+func getAlertFilter() string {
+	alertsMu.Lock()
+	defer alertsMu.Unlock()
+	return alertFilter
+}
+
+// exportAlerts writes the entries shown by the alerts pane to a CSV file in the working directory, oldest first
+func exportAlerts(g *gocui.Gui) error {
+	entries, _ := visibleAlerts()
+	path := fmt.Sprintf("alerts-%v.csv", time.Now().Format("20060102-150405"))
+
+	err := writeAlertsCSV(path, entries)
+	message := fmt.Sprintf("Exported %d alerts to %v, time = %s\n", len(entries), path, time.Now().Format(time.RFC1123))
+	if err != nil {
+		message = color.RedString("Alert export failed: %v, time = %s\n", err, time.Now().Format(time.RFC1123))
+	}
+	addEntry(g, messageEntry(time.Now(), message))
+	return nil
+}
+
+func writeAlertsCSV(path string, entries []logEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"time", "severity", "url", "up", "message"})
+	for _, e := range entries {
+		url, up := "", ""
+		if e.alert != nil {
+			url, up = secret.Redact(e.alert.URL), fmt.Sprint(e.alert.Up)
+		}
+		w.Write([]string{e.time.Format(time.RFC3339), e.severity(), url, up, strings.TrimSpace(e.text)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// openSearch opens the input line of the alerts filter, prefilled with the current one
+func openSearch(g *gocui.Gui) error {
+	v, err := openOverlay(g, "search")
+	if err != nil {
+		return err
+	}
+	v.Title = " Search the alerts: text, severity:critical, site:name (Enter to apply, Esc to clear) "
+	v.Editable = true
+	current := getAlertFilter()
+	fmt.Fprint(v, current)
+	if err := v.SetCursor(len(current), 0); err != nil {
+		return err
+	}
+	g.Cursor = true
+	return nil
+}
+
+// closeSearch closes the input line, applying the typed filter or clearing the current one
+func closeSearch(g *gocui.Gui, v *gocui.View, apply bool) error {
+	f := ""
+	if apply {
+		f = v.Buffer()
+	}
+	setAlertFilter(g, f)
+	g.Cursor = false
+	return closeOverlay(g, "search")
+}
+
+// showSiteAlerts restricts the alerts pane to the alerts of the selected website
+func showSiteAlerts(g *gocui.Gui) error {
+	dataMu.Lock()
+	rows := focusedRows()
+	url := ""
+	if selected < len(rows) {
+		url = rows[selected].url
+	}
+	dataMu.Unlock()
+	if url == "" {
+		return nil
+	}
+	setAlertFilter(g, "site:"+url)
+	return nil
+}
@@ -0,0 +1,109 @@
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/statsagent"
+)
+
+// sparkPoints is the number of points of the sparklines of the stats views
+const sparkPoints = 20
+
+// chartHeight is the number of lines of the response time chart of the detail pane
+const chartHeight = 8
+
+var blocks = []rune("▁▂▃▄▅▆▇█")
+
+// responseTimes returns the average response time of each point in milliseconds, -1 for the points without a successful check
+func responseTimes(points []statsagent.SeriesPoint) []float64 {
+	res := make([]float64, len(points))
+	for i, p := range points {
+		res[i] = -1
+		if p.Successes > 0 {
+			res[i] = float64(p.AvgResponseTime) / float64(time.Millisecond)
+		}
+	}
+	return res
+}
+
+// availabilities returns the availability of each point, -1 for the points without a check outside maintenance
+func availabilities(points []statsagent.SeriesPoint) []float64 {
+	res := make([]float64, len(points))
+	for i, p := range points {
+		res[i] = -1
+		if p.Counted > 0 {
+			res[i] = p.Availability
+		}
+	}
+	return res
+}
+
+// maxValue returns the largest value, at least 0
+func maxValue(values []float64) float64 {
+	max := 0.0
+	for _, value := range values {
+		if value > max {
+			max = value
+		}
+	}
+	return max
+}
+
+// sparkline draws values between 0 and max with a block per value, negative values are left blank
+func sparkline(values []float64, max float64) string {
+	var b strings.Builder
+	for _, value := range values {
+		switch {
+		case value < 0:
+			b.WriteRune(' ')
+		case max <= 0:
+			b.WriteRune(blocks[0])
+		default:
+			i := int(value / max * float64(len(blocks)-1))
+			if i >= len(blocks) {
+				i = len(blocks) - 1
+			}
+			b.WriteRune(blocks[i])
+		}
+	}
+	return b.String()
+}
+
+// writeChart draws values between 0 and their maximum as vertical bars of height lines, with the scale on the left
+// negative values are left blank
+func writeChart(w io.Writer, values []float64, height int, unit string) {
+	max := maxValue(values)
+	for line := height; line > 0; line-- {
+		label := ""
+		switch line {
+		case height:
+			label = fmt.Sprintf("%.0f%v", max, unit)
+		case 1:
+			label = "0" + unit
+		}
+		var b strings.Builder
+		for _, value := range values {
+			// the part of the bar in this line, from 0 to 1
+			fill := 0.0
+			if value >= 0 && max > 0 {
+				fill = value/max*float64(height) - float64(line-1)
+			}
+			switch {
+			case fill <= 0:
+				if line == 1 && value >= 0 {
+					b.WriteRune(blocks[0])
+				} else {
+					b.WriteRune(' ')
+				}
+			case fill >= 1:
+				b.WriteRune(blocks[len(blocks)-1])
+			default:
+				b.WriteRune(blocks[int(fill*float64(len(blocks)-1))])
+			}
+		}
+		fmt.Fprintf(w, "%8v │%v\n", label, b.String())
+	}
+}
@@ -0,0 +1,525 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/jroimartin/gocui"
+)
+
+// column is a sortable column of the stats views
+type column struct {
+	name string
+	less func(a, b row) bool
+}
+
+var columns = []column{
+	{"website", func(a, b row) bool { return a.name < b.name }},
+	{"state", func(a, b row) bool { return severity(a.state) < severity(b.state) }},
+	{"availability", func(a, b row) bool { return a.stats.Availability < b.stats.Availability }},
+	{"avg rt", func(a, b row) bool { return a.stats.AvgResponseTime < b.stats.AvgResponseTime }},
+	{"max rt", func(a, b row) bool { return a.stats.MaxResponseTime < b.stats.MaxResponseTime }},
+	{"avg ttfb", func(a, b row) bool { return a.stats.AvgTimeToFirstByte < b.stats.AvgTimeToFirstByte }},
+	{"max ttfb", func(a, b row) bool { return a.stats.MaxTimeToFirstByte < b.stats.MaxTimeToFirstByte }},
+}
+
+// The interactive state of the stats views, guarded by dataMu
+var (
+	// sortColumn is the index of the column the rows are sorted by, -1 keeps the configuration order
+	sortColumn = -1
+	descending bool
+	// filter is applied to the rows of every view, see matchesFilter
+	filter string
+	// focused is the index of the pane that gets the keys, the stats views then the alerts pane (see panes),
+	// selected is the index of the selected row when a stats view is focused
+	focused  int
+	selected int
+	// detailURL is the website shown in the detail pane, empty when the pane is closed,
+	// detailView is the index of the view it was opened from
+	detailURL  string
+	detailView int
+	// paused freezes the views and the alerts pane, the stats of the views and the alerts keep being collected and are drawn on resume
+	paused bool
+)
+
+const helpText = `Tab          focus the next pane
+↑ ↓          select a row of a view, or scroll the alerts, detail and help panes
+PgUp PgDn    move a page up or down
+← →          scroll to the left or to the right
+1 to 7       sort the rows by a column, again to reverse the order
+/            filter the rows: words searched in the URLs, key=value for a tag,
+             state:down for a state, group:name for a group
+             or, in the alerts pane, search the alerts: words searched in the messages,
+             severity:critical for a severity, site:name for the alerts of some websites
+f            show the alerts of the selected website
+e            export the alerts shown to a CSV file
+Enter        open the detail pane of the selected website, or the websites of a group
+Esc          close the detail pane, the filter line or this help
+g            switch every view between group rows and website rows
+a            acknowledge the ongoing incidents
+[ ]          show the dashboard a minute earlier or later
+{ }          show the dashboard an hour earlier or later
+t            type the time to show
+n            go back to the current time
++            add a website
+p            pause or resume the dashboard
+?            show or hide this help
+Ctrl+C       quit`
+
+// arrange filters and sorts the rows of a view
+func arrange(rows []row, filter string, column int, descending bool) []row {
+	res := make([]row, 0, len(rows))
+	for _, r := range rows {
+		if matchesFilter(r, filter) {
+			res = append(res, r)
+		}
+	}
+	if column >= 0 && column < len(columns) {
+		less := columns[column].less
+		sort.SliceStable(res, func(i, j int) bool {
+			if descending {
+				return less(res[j], res[i])
+			}
+			return less(res[i], res[j])
+		})
+	}
+	return res
+}
+
+// matchesFilter tells if a row matches every space-separated term of the filter:
+// state:down matches the rows in this state, group:name the websites of a group, key=value the rows with this tag,
+// and other terms are searched in the name of the row, ignoring the case
+func matchesFilter(r row, filter string) bool {
+	for _, term := range strings.Fields(filter) {
+		switch {
+		case strings.HasPrefix(term, "state:"):
+			if r.state != strings.TrimPrefix(term, "state:") {
+				return false
+			}
+		case strings.HasPrefix(term, "group:"):
+			if !anyWebsite(r.websites, func(ws monitor.Website) bool { return ws.Group == strings.TrimPrefix(term, "group:") }) {
+				return false
+			}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(term, "=", 2)
+			if !anyWebsite(r.websites, func(ws monitor.Website) bool { return ws.HasTags(map[string]string{parts[0]: parts[1]}) }) {
+				return false
+			}
+		default:
+			if !strings.Contains(strings.ToLower(r.name), strings.ToLower(term)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func anyWebsite(websites []monitor.Website, f func(monitor.Website) bool) bool {
+	for _, ws := range websites {
+		if f(ws) {
+			return true
+		}
+	}
+	return false
+}
+
+// panes returns the names of the panes the focus cycles through: the stats views, then the alerts pane
+func panes() []string {
+	names := make([]string, 0)
+	for index := range getViews() {
+		names = append(names, viewName(index))
+	}
+	return append(names, "alerts")
+}
+
+// overlays are the panes drawn over the others, the last one open gets the keys
+var overlays = []string{"detail", "help", "filter", "search", "time", "add", "add-url", "add-interval", "add-status", "add-persist"}
+
+// overlayPosition returns the coordinates of an overlay, for a terminal of the given size
+func overlayPosition(name string, maxX, maxY int) (int, int, int, int) {
+	if strings.HasPrefix(name, "add") {
+		return formPosition(name, maxX, maxY)
+	}
+	if name == "filter" || name == "search" || name == "time" {
+		return 0, maxY - 3, maxX - 1, maxY - 1
+	}
+	return maxX / 10, maxY / 10, maxX - maxX/10, maxY - maxY/10
+}
+
+// openOverlay creates an overlay, or clears it when it is already open, and gives it the keys
+func openOverlay(g *gocui.Gui, name string) (*gocui.View, error) {
+	maxX, maxY := g.Size()
+	x0, y0, x1, y1 := overlayPosition(name, maxX, maxY)
+	v, err := g.SetView(name, x0, y0, x1, y1)
+	if err != nil && err != gocui.ErrUnknownView {
+		return nil, err
+	}
+	v.Clear()
+	if err := v.SetOrigin(0, 0); err != nil {
+		return nil, err
+	}
+	if _, err := g.SetViewOnTop(name); err != nil {
+		return nil, err
+	}
+	_, err = g.SetCurrentView(name)
+	return v, err
+}
+
+// closeOverlay deletes an overlay, and gives the keys back to the overlay below or to the focused pane
+func closeOverlay(g *gocui.Gui, name string) error {
+	if err := g.DeleteView(name); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	return restoreFocus(g)
+}
+
+// restoreFocus gives the keys to the last open overlay, or to the focused pane
+func restoreFocus(g *gocui.Gui) error {
+	dataMu.Lock()
+	names := panes()
+	name := names[focused%len(names)]
+	dataMu.Unlock()
+	for _, overlay := range overlays {
+		if _, err := g.View(overlay); err == nil {
+			name = overlay
+		}
+	}
+	_, err := g.SetCurrentView(name)
+	return err
+}
+
+// focusNext gives the keys to the next pane
+func focusNext(g *gocui.Gui) error {
+	dataMu.Lock()
+	focused = (focused + 1) % len(panes())
+	selected = 0
+	redrawViews(g)
+	dataMu.Unlock()
+	return restoreFocus(g)
+}
+
+// focusedRows returns the rows displayed by the focused view, the caller must hold dataMu
+func focusedRows() []row {
+	views := getViews()
+	data, ok := lastData[focused]
+	if !ok || focused >= len(views) {
+		return nil
+	}
+	return arrange(rows(data, views[focused].Grouped != flipGrouping), filter, sortColumn, descending)
+}
+
+// redrawViews redraws every stats view with its last data, the caller must hold dataMu
+func redrawViews(g *gocui.Gui) {
+	views := getViews()
+	for index, data := range lastData {
+		if index >= len(views) {
+			continue
+		}
+		index, data, grouped := index, data, views[index].Grouped != flipGrouping
+		g.Update(func(g *gocui.Gui) error {
+			return drawView(g, index, data, grouped)
+		})
+	}
+}
+
+// sortBy sorts the rows by a column, choosing the same column again reverses the order
+func sortBy(g *gocui.Gui, column int) {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	if sortColumn == column {
+		descending = !descending
+	} else {
+		sortColumn, descending = column, false
+	}
+	redrawViews(g)
+}
+
+// scroll moves the selected row of a stats view, or scrolls the other panes
+func scroll(g *gocui.Gui, v *gocui.View, dy int) error {
+	if v == nil {
+		return nil
+	}
+	if v.Name() == "alerts" || v.Name() == "detail" || v.Name() == "help" {
+		return scrollView(v, dy)
+	}
+
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	selected += dy
+	if n := len(focusedRows()); selected >= n {
+		selected = n - 1
+	}
+	if selected < 0 {
+		selected = 0
+	}
+	redrawViews(g)
+	return nil
+}
+
+// page returns the number of lines shown by a pane
+func page(v *gocui.View) int {
+	if v == nil {
+		return 0
+	}
+	_, height := v.Size()
+	return height
+}
+
+// togglePause freezes or resumes the views and the alerts pane
+func togglePause(g *gocui.Gui) {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	paused = !paused
+	if !paused {
+		redrawViews(g)
+		drawAlerts(g)
+	}
+}
+
+func isPaused() bool {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	return paused
+}
+
+// toggleHelp shows or hides the list of keys
+func toggleHelp(g *gocui.Gui) error {
+	if _, err := g.View("help"); err == nil {
+		return closeOverlay(g, "help")
+	}
+	v, err := openOverlay(g, "help")
+	if err != nil {
+		return err
+	}
+	v.Title = " Keys (Esc to close) "
+	fmt.Fprintln(v, helpText)
+	return nil
+}
+
+// drillDown opens the detail pane of the selected website, it runs on the main loop
+// on a group row, it shows the websites of the group instead
+func drillDown(g *gocui.Gui) error {
+	dataMu.Lock()
+	rows := focusedRows()
+	if selected >= len(rows) {
+		dataMu.Unlock()
+		return nil
+	}
+	r := rows[selected]
+	if r.url == "" {
+		filter = strings.TrimSpace(filter + " group:" + r.websites[0].Group)
+		flipGrouping = !flipGrouping
+		selected = 0
+		redrawViews(g)
+		dataMu.Unlock()
+		return nil
+	}
+	detailURL, detailView = r.url, focused
+	timeFrame := getViews()[focused].TimeFrame
+	dataMu.Unlock()
+
+	v, err := openOverlay(g, "detail")
+	if err != nil {
+		return err
+	}
+	v.Title = " " + r.url + " (Esc to close) "
+	v.Wrap = true
+	fmt.Fprintln(v, "Loading the recent checks...")
+	go refreshDetail(g, r.websites[0], timeFrame)
+	return nil
+}
+
+// closeDetail closes the detail pane
+func closeDetail(g *gocui.Gui) error {
+	dataMu.Lock()
+	detailURL = ""
+	dataMu.Unlock()
+	return closeOverlay(g, "detail")
+}
+
+// setFilter applies a new filter to every view
+func setFilter(g *gocui.Gui, f string) {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	filter = strings.TrimSpace(f)
+	selected = 0
+	redrawViews(g)
+}
+
+func getFilter() string {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	return filter
+}
+
+// typing tells if keys go to an input line rather than to the dashboard
+func typing(v *gocui.View) bool {
+	return v != nil && v.Editable
+}
+
+// onRune makes a handler for a letter or digit key, the key is written instead when typing
+func onRune(ch rune, handler func(g *gocui.Gui) error) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if typing(v) {
+			v.EditWrite(ch)
+			return nil
+		}
+		return handler(g)
+	}
+}
+
+// onKey makes a handler for a special key, that does nothing when typing
+func onKey(handler func(g *gocui.Gui, v *gocui.View) error) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, v *gocui.View) error {
+		if typing(v) {
+			return nil
+		}
+		return handler(g, v)
+	}
+}
+
+// initControls binds the keys to move between the panes, sort, filter and select the rows, and open the detail pane
+func initControls(g *gocui.Gui) error {
+	for i := range columns {
+		i := i
+		if err := g.SetKeybinding("", rune('1'+i), gocui.ModNone, onRune(rune('1'+i), func(g *gocui.Gui) error {
+			sortBy(g, i)
+			return nil
+		})); err != nil {
+			return fmt.Errorf("error while setting the sort keys: %v", err)
+		}
+	}
+	runes := map[rune]func(g *gocui.Gui) error{
+		'/': func(g *gocui.Gui) error {
+			if v := g.CurrentView(); v != nil && v.Name() == "alerts" {
+				return openSearch(g)
+			}
+			return openFilter(g)
+		},
+		'f': showSiteAlerts,
+		'e': exportAlerts,
+		'+': openForm,
+		'?': toggleHelp,
+		'p': func(g *gocui.Gui) error {
+			togglePause(g)
+			return nil
+		},
+		't': openTimeInput,
+		'n': func(g *gocui.Gui) error {
+			travelTo(g, time.Time{})
+			return nil
+		},
+	}
+	for ch, d := range map[rune]time.Duration{'[': -time.Minute, ']': time.Minute, '{': -time.Hour, '}': time.Hour} {
+		d := d
+		runes[ch] = func(g *gocui.Gui) error {
+			travel(g, d)
+			return nil
+		}
+	}
+	for ch, handler := range runes {
+		if err := g.SetKeybinding("", ch, gocui.ModNone, onRune(ch, handler)); err != nil {
+			return fmt.Errorf("error while setting the %q key: %v", ch, err)
+		}
+	}
+
+	keys := map[gocui.Key]func(g *gocui.Gui, v *gocui.View) error{
+		gocui.KeyArrowUp:    func(g *gocui.Gui, v *gocui.View) error { return scroll(g, v, -1) },
+		gocui.KeyArrowDown:  func(g *gocui.Gui, v *gocui.View) error { return scroll(g, v, 1) },
+		gocui.KeyPgup:       func(g *gocui.Gui, v *gocui.View) error { return scroll(g, v, -page(v)) },
+		gocui.KeyPgdn:       func(g *gocui.Gui, v *gocui.View) error { return scroll(g, v, page(v)) },
+		gocui.KeyArrowLeft:  func(g *gocui.Gui, v *gocui.View) error { return scrollViewX(v, -8) },
+		gocui.KeyArrowRight: func(g *gocui.Gui, v *gocui.View) error { return scrollViewX(v, 8) },
+	}
+	for key, handler := range keys {
+		if err := g.SetKeybinding("", key, gocui.ModNone, onKey(handler)); err != nil {
+			return fmt.Errorf("error while setting the navigation keys: %v", err)
+		}
+	}
+
+	if err := g.SetKeybinding("", gocui.KeyTab, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		switch {
+		case isFormField(v):
+			return nextField(g, v)
+		// the overlays keep the keys until they are closed
+		case typing(v), v != nil && (v.Name() == "detail" || v.Name() == "help"):
+			return nil
+		}
+		return focusNext(g)
+	}); err != nil {
+		return fmt.Errorf("error while setting the focus key: %v", err)
+	}
+	if err := g.SetKeybinding("", gocui.KeyEnter, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		if typing(v) {
+			return closeInput(g, v, true)
+		}
+		if v != nil && (v.Name() == "detail" || v.Name() == "help") {
+			return nil
+		}
+		return drillDown(g)
+	}); err != nil {
+		return fmt.Errorf("error while setting the drill-down key: %v", err)
+	}
+	if err := g.SetKeybinding("", gocui.KeyEsc, gocui.ModNone, func(g *gocui.Gui, v *gocui.View) error {
+		switch {
+		case typing(v):
+			return closeInput(g, v, false)
+		case v != nil && v.Name() == "help":
+			return closeOverlay(g, "help")
+		case v != nil && v.Name() == "detail":
+			return closeDetail(g)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("error while setting the escape key: %v", err)
+	}
+	return nil
+}
+
+// openFilter opens the input line of the filter, prefilled with the current one
+func openFilter(g *gocui.Gui) error {
+	v, err := openOverlay(g, "filter")
+	if err != nil {
+		return err
+	}
+	v.Title = " Filter: text, key=value, state:down, group:name (Enter to apply, Esc to clear) "
+	v.Editable = true
+	current := getFilter()
+	fmt.Fprint(v, current)
+	if err := v.SetCursor(len(current), 0); err != nil {
+		return err
+	}
+	g.Cursor = true
+	return nil
+}
+
+// closeInput closes the filter, the alerts search, the time input line or the form adding a website
+func closeInput(g *gocui.Gui, v *gocui.View, apply bool) error {
+	if isFormField(v) {
+		if apply {
+			return submitForm(g)
+		}
+		return closeForm(g)
+	}
+	switch v.Name() {
+	case "time":
+		return closeTimeInput(g, v, apply)
+	case "search":
+		return closeSearch(g, v, apply)
+	}
+	return closeFilter(g, v, apply)
+}
+
+// closeFilter closes the input line, applying the typed filter or clearing the current one
+func closeFilter(g *gocui.Gui, v *gocui.View, apply bool) error {
+	f := ""
+	if apply {
+		f = v.Buffer()
+	}
+	setFilter(g, f)
+	g.Cursor = false
+	return closeOverlay(g, "filter")
+}
@@ -3,52 +3,91 @@ package dashboard
 import (
 	"context"
 	"fmt"
-	"log"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
 	"github.com/ayoubed/datadog-home-project/statsagent"
 	"github.com/fatih/color"
 	"github.com/jroimartin/gocui"
 	"golang.org/x/sync/errgroup"
 )
 
+// alertHistorySize is the number of stored alerts shown when the dashboard starts
+const alertHistorySize = 100
+
 // View represents one entity on the Gui
 // views display stats for a user-defined timeframe
 // they are updated following a user-defined interval
+// Tags restricts the view to the websites having all of them, Grouped shows a row per group of websites
 type View struct {
-	UpdateInterval int   `json:"updateInterval"`
-	TimeFrame      int64 `json:"timeFrame"`
+	UpdateInterval int               `json:"updateInterval"`
+	TimeFrame      int64             `json:"timeFrame"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	Grouped        bool              `json:"grouped,omitempty"`
+}
+
+// viewData is the last data shown by a view, kept to redraw it without waiting for the next update
+// series are the response times and availabilities of each website over the timeframe, for the sparklines,
+// and states the states of the websites at the end of the timeframe
+type viewData struct {
+	websites []monitor.Website
+	stats    map[string]statsagent.WebsiteStats
+	series   map[string][]statsagent.SeriesPoint
+	states   map[string]string
 }
 
+var (
+	// currentViews are the views displayed by the layout, they can be replaced while the dashboard runs
+	currentViews []View
+	viewsMu      sync.RWMutex
+
+	// lastData is the data of each displayed view, by view index
+	lastData map[int]viewData = make(map[int]viewData)
+	// flipGrouping is toggled with the g key, it switches every view between group rows and website rows
+	flipGrouping bool
+	dataMu       sync.Mutex
+)
+
 // Run displays the statistics, and alerts in our terminal
-func Run(ctx context.Context, urls []string, views []View, alertc chan string, done context.CancelFunc) error {
+// sites is called on every update, so the monitored websites can change while the dashboard runs,
+// and new views can be sent through viewc
+// add is used by the form adding a website, the form is disabled when it's nil
+func Run(ctx context.Context, sites func() []monitor.Website, add AddFunc, views []View, viewc <-chan []View, alertc chan string, done context.CancelFunc) error {
 	g, err := gocui.NewGui(gocui.OutputNormal)
 	if err != nil {
 		return fmt.Errorf("error creating GUI: %v", err)
 	}
 	defer g.Close()
+	// Esc closes the filter and the overlays
+	g.InputEsc = true
+	// the frame of the pane that gets the keys is highlighted
+	g.Highlight = true
+	g.SelFgColor = gocui.ColorGreen
 
 	// set the layout of the GUI
-	g.SetManagerFunc(layout(g, views))
+	setViews(views)
+	addWebsite = add
+	g.SetManagerFunc(layout)
 
 	// launch goroutines to continuously update our views
 	errg, gctx := errgroup.WithContext(ctx)
 
-	for _, view := range views {
-		view := view
-		errg.Go(func() error {
-			return updateView(gctx, view, g, urls)
-		})
-	}
+	errg.Go(func() error {
+		return runViews(gctx, g, sites, views, viewc)
+	})
 
 	errg.Go(func() error {
 		return monitorAlertChan(gctx, g, alertc)
 	})
 
 	// Set key bindings for the GUI
-	if err := initKeybindings(g, done); err != nil {
+	if err := initKeybindings(ctx, g, alertc, done); err != nil {
 		return err
 	}
 
@@ -63,7 +102,68 @@ func Run(ctx context.Context, urls []string, views []View, alertc chan string, d
 	return nil
 }
 
-func initKeybindings(g *gocui.Gui, done context.CancelFunc) error {
+func setViews(views []View) {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	currentViews = views
+}
+
+func getViews() []View {
+	viewsMu.RLock()
+	defer viewsMu.RUnlock()
+	return currentViews
+}
+
+// runViews runs an update goroutine per view, and restarts them when new views are received
+func runViews(ctx context.Context, g *gocui.Gui, sites func() []monitor.Website, views []View, viewc <-chan []View) error {
+	for {
+		errg, vctx := errgroup.WithContext(ctx)
+		vctx, cancel := context.WithCancel(vctx)
+		for index, view := range views {
+			index, view := index, view
+			errg.Go(func() error {
+				return updateView(vctx, index, view, g, sites)
+			})
+		}
+
+		select {
+		case newViews := <-viewc:
+			cancel()
+			if err := errg.Wait(); err != nil {
+				return err
+			}
+			old := views
+			views = newViews
+			setViews(views)
+			dataMu.Lock()
+			lastData = make(map[int]viewData)
+			if focused > len(views) {
+				focused, selected = 0, 0
+			}
+			dataMu.Unlock()
+
+			// drop the gocui views that are not displayed anymore, the layout creates the new ones
+			g.Update(func(g *gocui.Gui) error {
+				for index := range old {
+					if err := g.DeleteView(viewName(index)); err != nil && err != gocui.ErrUnknownView {
+						return err
+					}
+				}
+				return nil
+			})
+		case <-vctx.Done():
+			cancel()
+			return errg.Wait()
+		}
+	}
+}
+
+// viewName is the name of the gocui view of a stats view
+func viewName(index int) string {
+	return "stats-" + strconv.Itoa(index)
+}
+
+func initKeybindings(ctx context.Context, g *gocui.Gui, alertc chan string, done context.CancelFunc) error {
 	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone,
 		func(g *gocui.Gui, v *gocui.View) error {
 			done()
@@ -71,138 +171,433 @@ func initKeybindings(g *gocui.Gui, done context.CancelFunc) error {
 		}); err != nil {
 		return fmt.Errorf("error while trying to close our GUI: %v", err)
 	}
-	if err := g.SetKeybinding("stdin", gocui.KeyArrowUp, gocui.ModNone,
-		func(g *gocui.Gui, v *gocui.View) error {
-			scrollView(v, -1)
+	if err := g.SetKeybinding("", 'a', gocui.ModNone,
+		onRune('a', func(g *gocui.Gui) error {
+			acknowledgeIncidents(ctx, alertc)
 			return nil
-		}); err != nil {
-
-		log.Panicln(err)
+		})); err != nil {
+		return fmt.Errorf("error while setting the acknowledge key: %v", err)
 	}
-	if err := g.SetKeybinding("stdin", gocui.KeyArrowDown, gocui.ModNone,
-		func(g *gocui.Gui, v *gocui.View) error {
-			scrollView(v, 1)
+	if err := g.SetKeybinding("", 'g', gocui.ModNone,
+		onRune('g', func(g *gocui.Gui) error {
+			toggleGrouping(g)
 			return nil
-		}); err != nil {
-
-		log.Panicln(err)
+		})); err != nil {
+		return fmt.Errorf("error while setting the grouping key: %v", err)
 	}
-	return nil
+	return initControls(g)
 }
 
-func updateView(ctx context.Context, currentView View, g *gocui.Gui, urls []string) error {
+func updateView(ctx context.Context, index int, currentView View, g *gocui.Gui, sites func() []monitor.Website) error {
 
 	ticker := time.NewTicker(time.Duration(currentView.UpdateInterval) * time.Second)
+	defer ticker.Stop()
 	for {
+		dataMu.Lock()
+		changed := originChanged
+		dataMu.Unlock()
+
 		select {
 		case <-ctx.Done():
-			ticker.Stop()
 			return nil
+		case <-changed:
+			// the time shown changed, don't wait for the next tick
+			if err := refreshView(index, currentView, g, sites, time.Now()); err != nil {
+				return err
+			}
 		case t := <-ticker.C:
-			// Grab the latest stats over the given timeframe
-			res, err := statsagent.GetStats(urls, t, currentView.TimeFrame)
-			if err != nil {
-				return fmt.Errorf("error while getting stats to update view: %v", err)
+			if err := refreshView(index, currentView, g, sites, t); err != nil {
+				return err
 			}
+		}
+	}
+}
 
-			// update the GUI with the latest stats
-			g.Update(func(g *gocui.Gui) error {
-				v, err := g.View(strconv.Itoa(int(currentView.TimeFrame)))
-				if err != nil {
-					return fmt.Errorf("error getting view in update function: %v", err)
-				}
-				v.Clear()
+// refreshView computes the stats of a view, ending now or at the time the dashboard shows, and draws them
+// while the dashboard is paused the stats are kept for the resume, without being drawn
+func refreshView(index int, currentView View, g *gocui.Gui, sites func() []monitor.Website, now time.Time) error {
+	dataMu.Lock()
+	t, live := at(now), origin.IsZero()
+	dataMu.Unlock()
 
-				// pretty print the stats to our view
-				header := color.New(color.FgYellow, color.Bold)
-				header.Fprintln(v, fmt.Sprintf("%-30v %12v %12v %12v %12v %12v %25v\n", "website", "availability", "avg rt", "max rt", "avg ttfb", "max ttfb", "status codes"))
+	data, err := collect(currentView, sites(), t, live, sparkPoints)
+	if err != nil {
+		return err
+	}
+	websites := data.websites
+	dataMu.Lock()
+	lastData[index] = data
+	grouped := currentView.Grouped != flipGrouping
+	detail := ""
+	if index == detailView {
+		detail = detailURL
+	}
+	frozen := paused
+	dataMu.Unlock()
+	if frozen {
+		return nil
+	}
 
-				for _, url := range urls {
-					value := res[url]
-					statusCodeSlice := make([]string, 0)
-					for code, count := range value.StatusCodeCount {
-						statusCodeSlice = append(statusCodeSlice, fmt.Sprintf("%v:%v", code, count))
-					}
-					statusCodeStr := fmt.Sprintf("[%v]", strings.Join(statusCodeSlice, " "))
-					fmt.Fprintln(v, fmt.Sprintf("%-30v %11.2f%% %10.2fms %10.2fms %10.2fms %10.2fms %25v", url, 100*value.Availability, float64(value.AvgResponseTime)/float64(time.Millisecond), float64(value.MaxResponseTime)/float64(time.Millisecond), float64(value.AvgTimeToFirstByte)/float64(time.Millisecond), float64(value.MaxTimeToFirstByte)/float64(time.Millisecond), statusCodeStr))
-				}
-				return nil
-			})
+	// update the GUI with the latest stats
+	g.Update(func(g *gocui.Gui) error {
+		return drawView(g, index, data, grouped)
+	})
+
+	// and the detail pane, when it shows a website of this view
+	for _, ws := range websites {
+		if ws.URL == detail {
+			refreshDetail(g, ws, currentView.TimeFrame)
 		}
 	}
+	return nil
 }
 
-func monitorAlertChan(ctx context.Context, g *gocui.Gui, alertc chan string) error {
-	var alerts []string
-	for {
-		select {
-		case alertMessage := <-alertc:
-			alerts = append(alerts, alertMessage)
-			if err := updateAlertView(g, alerts); err != nil {
-				return fmt.Errorf("error while updating the alert view: %v", err)
+// collect computes the data of a view over its timeframe ending at t, live tells if t is the current time
+// the series have the given number of points
+func collect(currentView View, sites []monitor.Website, t time.Time, live bool, points int) (viewData, error) {
+	websites := make([]monitor.Website, 0)
+	urls := make([]string, 0)
+	for _, ws := range sites {
+		if ws.HasTags(currentView.Tags) {
+			websites = append(websites, ws)
+			urls = append(urls, ws.URL)
+		}
+	}
+	// Grab the latest stats over the given timeframe
+	res, err := statsagent.GetStats(urls, t, currentView.TimeFrame)
+	if err != nil {
+		return viewData{}, fmt.Errorf("error while getting stats to update view: %v", err)
+	}
+
+	series := make(map[string][]statsagent.SeriesPoint)
+	states := make(map[string]string)
+	for _, url := range urls {
+		if series[url], err = statsagent.GetSeries(url, t, currentView.TimeFrame, points); err != nil {
+			return viewData{}, fmt.Errorf("error while getting the series to update view: %v", err)
+		}
+		// in the past, the state of a website is the one of its last alert
+		if live {
+			states[url], _ = alerting.Status(url)
+		} else if states[url], err = alerting.StatusAt(url, t); err != nil {
+			return viewData{}, fmt.Errorf("error while getting the past states to update view: %v", err)
+		}
+	}
+	return viewData{websites: websites, stats: res, series: series, states: states}, nil
+}
+
+// toggleGrouping switches every view between group rows and website rows, and redraws them right away
+func toggleGrouping(g *gocui.Gui) {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	flipGrouping = !flipGrouping
+	selected = 0
+	redrawViews(g)
+}
+
+// row is a line of a stats view, a website or a group of websites
+// url is empty for a group, websites are the websites of the row
+type row struct {
+	name     string
+	url      string
+	state    string
+	stats    statsagent.WebsiteStats
+	series   []statsagent.SeriesPoint
+	websites []monitor.Website
+}
+
+// Row is a line of a view for the other frontends, like the web dashboard: a website, or a group of websites with an empty URL
+type Row struct {
+	Name   string
+	URL    string
+	State  string
+	Stats  statsagent.WebsiteStats
+	Series []statsagent.SeriesPoint
+}
+
+// Rows computes the rows of a view at time t like the dashboard does: the websites having the tags of the view,
+// gathered by group when the view is grouped, with series of the given number of points
+func Rows(view View, sites []monitor.Website, t time.Time, points int) ([]Row, error) {
+	data, err := collect(view, sites, t, true, points)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Row, 0, len(data.websites))
+	for _, r := range rows(data, view.Grouped) {
+		res = append(res, Row{Name: r.name, URL: r.url, State: r.state, Stats: r.stats, Series: r.series})
+	}
+	return res, nil
+}
+
+// rows returns a row per website, or per group of websites when grouped is set
+// the state of a group is the worst state of its websites, websites without a group are gathered in the "ungrouped" row
+func rows(data viewData, grouped bool) []row {
+	res := make([]row, 0, len(data.websites))
+	if !grouped {
+		for _, ws := range data.websites {
+			state := data.states[ws.URL]
+			res = append(res, row{name: secret.Redact(ws.URL), url: ws.URL, state: state, stats: data.stats[ws.URL], series: data.series[ws.URL], websites: []monitor.Website{ws}})
+		}
+		return res
+	}
+
+	order := make([]string, 0)
+	groups := make(map[string][]monitor.Website)
+	for _, ws := range data.websites {
+		name := ws.Group
+		if name == "" {
+			name = "ungrouped"
+		}
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], ws)
+	}
+
+	for _, name := range order {
+		stats := make([]statsagent.WebsiteStats, 0, len(groups[name]))
+		series := make([][]statsagent.SeriesPoint, 0, len(groups[name]))
+		state := ""
+		for _, ws := range groups[name] {
+			stats = append(stats, data.stats[ws.URL])
+			series = append(series, data.series[ws.URL])
+			if s := data.states[ws.URL]; severity(s) > severity(state) {
+				state = s
 			}
-		case <-ctx.Done():
-			return nil
 		}
+		res = append(res, row{name: fmt.Sprintf("%v (%d)", name, len(groups[name])), state: state, stats: statsagent.AggregateStats(stats), series: statsagent.AggregateSeries(series), websites: groups[name]})
 	}
+	return res
 }
 
-func updateAlertView(g *gocui.Gui, alerts []string) error {
+// severity orders the website states, from the best to the worst
+func severity(state string) int {
+	switch state {
+	case alerting.StatusUp:
+		return 1
+	case alerting.StatusFlapping:
+		return 2
+	case alerting.StatusDown:
+		return 3
+	}
+	return 0
+}
 
-	g.Update(func(g *gocui.Gui) error {
-		v, err := g.View("alerts")
-		if err != nil {
-			return err
+// drawView draws the rows of a view, filtered, sorted and with the selected row highlighted
+func drawView(g *gocui.Gui, index int, data viewData, grouped bool) error {
+	v, err := g.View(viewName(index))
+	if err != nil {
+		return fmt.Errorf("error getting view in update function: %v", err)
+	}
+	v.Clear()
+
+	dataMu.Lock()
+	viewRows := arrange(rows(data, grouped), filter, sortColumn, descending)
+	sorted, desc, current := sortColumn, descending, -1
+	if index == focused {
+		current = selected
+	}
+	dataMu.Unlock()
+
+	// pretty print the stats to our view, the sort column is marked with an arrow
+	names := make([]interface{}, 0, len(columns)+1)
+	for i, c := range columns {
+		name := c.name
+		if i == 0 && grouped {
+			name = "group"
+		}
+		if i == sorted {
+			name += map[bool]string{false: " ▲", true: " ▼"}[desc]
 		}
-		v.Clear()
+		names = append(names, name)
+	}
+	names = append(names, "status codes", "response time", "availability")
+	header := color.New(color.FgYellow, color.Bold)
+	header.Fprintln(v, fmt.Sprintf("%-30v %-9v %12v %12v %12v %12v %12v %25v  %-*v %-*v\n", names[0], names[1], names[2], names[3], names[4], names[5], names[6], names[7], sparkPoints, names[8], sparkPoints, names[9]))
 
-		for i := len(alerts) - 1; i >= 0; i-- {
-			fmt.Fprintln(v, alerts[i])
+	for i, r := range viewRows {
+		value := r.stats
+		statusCodeSlice := make([]string, 0)
+		for code, count := range value.StatusCodeCount {
+			statusCodeSlice = append(statusCodeSlice, fmt.Sprintf("%v:%v", code, count))
 		}
-		return nil
-	})
+		sort.Strings(statusCodeSlice)
+		statusCodeStr := fmt.Sprintf("[%v]", strings.Join(statusCodeSlice, " "))
+		times := responseTimes(r.series)
+		line := fmt.Sprintf("%-30v %-9v %11.2f%% %10.2fms %10.2fms %10.2fms %10.2fms %25v  %-*v %-*v", r.name, r.state, 100*value.Availability, float64(value.AvgResponseTime)/float64(time.Millisecond), float64(value.MaxResponseTime)/float64(time.Millisecond), float64(value.AvgTimeToFirstByte)/float64(time.Millisecond), float64(value.MaxTimeToFirstByte)/float64(time.Millisecond), statusCodeStr,
+			sparkPoints, sparkline(times, maxValue(times)), sparkPoints, sparkline(availabilities(r.series), 1))
+		if i == current {
+			// reverse video
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		fmt.Fprintln(v, line)
+	}
+	if len(viewRows) == 0 && len(data.websites) > 0 {
+		fmt.Fprintln(v, "No website matches the filter")
+	}
+
+	// keep the selected row in sight, below the header and the blank line
+	if current >= 0 {
+		line := current + 2
+		_, height := v.Size()
+		ox, oy := v.Origin()
+		if current == 0 {
+			oy = 0
+		} else if line < oy {
+			oy = line
+		} else if line >= oy+height {
+			oy = line - height + 1
+		}
+		return v.SetOrigin(ox, oy)
+	}
 	return nil
 }
 
-func layout(g *gocui.Gui, views []View) func(*gocui.Gui) error {
-	maxX, maxY := g.Size()
-	return func(g *gocui.Gui) error {
-		// Set stats views
-		numViews := len(views) + 1 // number of views, plus the alert channel
-		for index, view := range views {
-			v, err := g.SetView(strconv.Itoa(int(view.TimeFrame)), 0, index*(maxY/numViews), maxX, (index+1)*(maxY/numViews))
-			if err != nil {
-				if err != gocui.ErrUnknownView {
-					log.Panic("Error setting views")
-				}
+// acknowledgeIncidents acknowledges every ongoing incident and reports it in the alerts view
+func acknowledgeIncidents(ctx context.Context, alertc chan string) {
+	urls := alerting.AcknowledgeAll()
+	message := "No ongoing incident to acknowledge\n"
+	if len(urls) > 0 {
+		message = fmt.Sprintf("Acknowledged incidents: %v, time = %s\n", strings.Join(urls, ", "), time.Now().Format(time.RFC1123))
+	}
 
-				loadingMessage := color.New(color.FgMagenta)
-				loadingMessage.Fprintln(v, fmt.Sprintf("\n\n%v One moment, we're waiting for statistics for the last %vs...", "⌛ ", view.TimeFrame))
-			}
-			v.FgColor = gocui.ColorCyan
-			v.Title = fmt.Sprintf(" Statistics for the last %vs (updated every %vs) ", view.TimeFrame, view.UpdateInterval)
-			v.Wrap = true
+	// the alerts channel is consumed by monitorAlertChan, don't block the GUI main loop
+	go func() {
+		select {
+		case alertc <- message:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// monitorAlertChan fills the alerts pane with the stored alerts, then with the new alerts and the messages sent through alertc
+func monitorAlertChan(ctx context.Context, g *gocui.Gui, alertc chan string) error {
+	// subscribe first so no alert is missed, the ones also found in the history are skipped
+	alerts, unsubscribe := alerting.Subscribe(alertHistorySize)
+	defer unsubscribe()
+
+	// start with the alerts stored by previous runs
+	history, err := alerting.History(alertHistorySize)
+	if err != nil {
+		return fmt.Errorf("error while loading the alert history: %v", err)
+	}
+	alertsMu.Lock()
+	for _, alert := range history {
+		alertLog = appendEntry(alertLog, alertEntry(alert))
+	}
+	alertsMu.Unlock()
+	drawAlerts(g)
+
+	for {
+		select {
+		case alert := <-alerts:
+			addEntry(g, alertEntry(alert))
+		case message := <-alertc:
+			addEntry(g, messageEntry(time.Now(), message))
+		case <-ctx.Done():
+			return nil
 		}
+	}
+}
 
-		// Set alerts view
-		v, err := g.SetView("alerts", 0, (numViews-1)*(maxY/numViews), maxX, maxY)
+// layout places the panes, it runs on every iteration of the main loop so it follows the size of the terminal
+func layout(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	views := getViews()
+	state := describeOrigin()
+	if isPaused() {
+		state += " [paused, p to resume]"
+	}
+
+	// Set stats views
+	numViews := len(views) + 1 // number of views, plus the alert channel
+	for index, view := range views {
+		v, err := g.SetView(viewName(index), 0, index*(maxY/numViews), maxX-1, (index+1)*(maxY/numViews)-1)
 		if err != nil {
 			if err != gocui.ErrUnknownView {
-				log.Panic("Error setting views")
+				return fmt.Errorf("error setting the views: %v", err)
 			}
+
+			loadingMessage := color.New(color.FgMagenta)
+			loadingMessage.Fprintln(v, fmt.Sprintf("\n\n%v One moment, we're waiting for statistics for the last %vs...", "⌛ ", view.TimeFrame))
 		}
 		v.FgColor = gocui.ColorCyan
-		v.Title = fmt.Sprintf(" Alerts ")
-		v.Wrap = true
-		return nil
+		v.Title = fmt.Sprintf(" Statistics for the last %vs (updated every %vs)%v%v ", view.TimeFrame, view.UpdateInterval, formatTags(view.Tags), state)
+		// long rows are scrolled horizontally rather than wrapped, so each row stays on one line
+		v.Wrap = false
+	}
+
+	// Set alerts view
+	v, err := g.SetView("alerts", 0, (numViews-1)*(maxY/numViews), maxX-1, maxY-1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return fmt.Errorf("error setting the views: %v", err)
+		}
+	}
+	v.FgColor = gocui.ColorCyan
+	v.Title = fmt.Sprintf(" Alerts%v (? for help) ", state)
+	v.Wrap = true
+
+	// follow the size of the terminal with the overlays too
+	for _, name := range overlays {
+		if _, err := g.View(name); err == nil {
+			x0, y0, x1, y1 := overlayPosition(name, maxX, maxY)
+			if _, err := g.SetView(name, x0, y0, x1, y1); err != nil {
+				return err
+			}
+		}
 	}
+
+	// give the keys to the focused pane when no pane has them, or when the one having them was replaced
+	if current := g.CurrentView(); current == nil {
+		return restoreFocus(g)
+	} else if v, err := g.View(current.Name()); err != nil || v != current {
+		return restoreFocus(g)
+	}
+	return nil
 }
 
+// formatTags describes the tag filter of a view, like " [env=prod team=payments]"
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return " [" + strings.Join(pairs, " ") + "]"
+}
+
+// scrollView scrolls a pane vertically, without going past its first or its last line
 func scrollView(v *gocui.View, dy int) error {
 	if v != nil {
 		v.Autoscroll = false
 		ox, oy := v.Origin()
-		if err := v.SetOrigin(ox, oy+dy); err != nil {
+		_, height := v.Size()
+		oy += dy
+		if last := len(v.BufferLines()) - height; oy > last {
+			oy = last
+		}
+		if oy < 0 {
+			oy = 0
+		}
+		if err := v.SetOrigin(ox, oy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scrollViewX scrolls a pane horizontally, without going past its first column
+func scrollViewX(v *gocui.View, dx int) error {
+	if v != nil {
+		ox, oy := v.Origin()
+		ox += dx
+		if ox < 0 {
+			ox = 0
+		}
+		if err := v.SetOrigin(ox, oy); err != nil {
 			return err
 		}
 	}
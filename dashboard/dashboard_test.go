@@ -0,0 +1,253 @@
+package dashboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+	"github.com/fatih/color"
+)
+
+func TestArrange(t *testing.T) {
+	website := func(url, state string, availability float64, group string, tags map[string]string) row {
+		ws := monitor.Website{URL: url, Group: group, Tags: tags}
+		return row{name: url, url: url, state: state, stats: statsagent.WebsiteStats{Availability: availability}, websites: []monitor.Website{ws}}
+	}
+	rows := []row{
+		website("https://shop.example.com", "up", 0.99, "shop", map[string]string{"env": "prod"}),
+		website("https://api.example.com", "down", 0.5, "shop", map[string]string{"env": "staging"}),
+		website("https://blog.example.com", "up", 1, "", map[string]string{"env": "prod"}),
+	}
+
+	tests := []struct {
+		filter     string
+		column     int
+		descending bool
+		want       []string
+	}{
+		{"", -1, false, []string{"https://shop.example.com", "https://api.example.com", "https://blog.example.com"}},
+		{"", 0, false, []string{"https://api.example.com", "https://blog.example.com", "https://shop.example.com"}},
+		{"", 2, true, []string{"https://blog.example.com", "https://shop.example.com", "https://api.example.com"}},
+		{"SHOP", -1, false, []string{"https://shop.example.com"}},
+		{"env=prod", 2, false, []string{"https://shop.example.com", "https://blog.example.com"}},
+		{"state:down", -1, false, []string{"https://api.example.com"}},
+		{"group:shop env=prod", -1, false, []string{"https://shop.example.com"}},
+		{"env=dev", -1, false, []string{}},
+	}
+	for _, test := range tests {
+		res := arrange(rows, test.filter, test.column, test.descending)
+		names := make([]string, 0, len(res))
+		for _, r := range res {
+			names = append(names, r.name)
+		}
+		if len(names) != len(test.want) {
+			t.Errorf("Filter %q, column %d: got %v, want %v", test.filter, test.column, names, test.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != test.want[i] {
+				t.Errorf("Filter %q, column %d: got %v, want %v", test.filter, test.column, names, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		max    float64
+		want   string
+	}{
+		{[]float64{0, 50, 100}, 100, "▁▄█"},
+		{[]float64{-1, 1, 0.5}, 1, " █▄"},
+		{[]float64{0, 0}, 0, "▁▁"},
+		{[]float64{}, 1, ""},
+	}
+	for _, test := range tests {
+		if res := sparkline(test.values, test.max); res != test.want {
+			t.Errorf("sparkline(%v, %v) = %q, want %q", test.values, test.max, res, test.want)
+		}
+	}
+}
+
+func TestChartSeries(t *testing.T) {
+	points := []statsagent.SeriesPoint{
+		{Count: 2, Counted: 2, Successes: 1, Availability: 0.5, AvgResponseTime: 200 * time.Millisecond},
+		{Count: 3, Counted: 0, Successes: 3, AvgResponseTime: 100 * time.Millisecond},
+		{},
+	}
+	if res := availabilities(points); len(res) != 3 || res[0] != 0.5 || res[1] != -1 || res[2] != -1 {
+		t.Errorf("Got availabilities %v, want [0.5 -1 -1]: the point with only maintenance checks has no availability", res)
+	}
+	if res := responseTimes(points); len(res) != 3 || res[0] != 200 || res[1] != 100 || res[2] != -1 {
+		t.Errorf("Got response times %v, want [200 100 -1]: the checks during maintenance have a response time", res)
+	}
+}
+
+func TestCheckMarks(t *testing.T) {
+	noColor := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = noColor })
+	green, yellow, red := color.GreenString("▮"), color.YellowString("▮"), color.RedString("▮")
+
+	ok := request.ResponseLog{Success: true}
+	failed := request.ResponseLog{}
+	maintenance := request.ResponseLog{Maintenance: true}
+	tests := []struct {
+		records []request.ResponseLog
+		width   int
+		want    string
+	}{
+		{[]request.ResponseLog{ok, failed, maintenance}, 10, green + red + yellow},
+		{[]request.ResponseLog{ok, ok, ok, failed, maintenance, ok}, 3, green + red + yellow},
+		{make([]request.ResponseLog, 100), 20, strings.Repeat(red, 20)},
+		{[]request.ResponseLog{ok}, 0, green},
+		{nil, 5, ""},
+	}
+	for _, test := range tests {
+		if res := checkMarks(test.records, test.width); res != test.want {
+			t.Errorf("checkMarks of %d checks in %v = %q, want %q", len(test.records), test.width, res, test.want)
+		}
+	}
+}
+
+func TestParseOrigin(t *testing.T) {
+	now := time.Date(2020, 5, 10, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"now", time.Time{}},
+		{"", time.Time{}},
+		{"90m", now.Add(-90 * time.Minute)},
+		{"-2h", now.Add(-2 * time.Hour)},
+		{"14:30", time.Date(2020, 5, 10, 14, 30, 0, 0, time.UTC)},
+		{"2020-05-09 08:15", time.Date(2020, 5, 9, 8, 15, 0, 0, time.UTC)},
+		{"2020-05-09T08:15:00Z", time.Date(2020, 5, 9, 8, 15, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		res, err := parseOrigin(test.input, now)
+		if err != nil {
+			t.Errorf("parseOrigin(%q) returned %v", test.input, err)
+			continue
+		}
+		if !res.Equal(test.want) {
+			t.Errorf("parseOrigin(%q) = %v, want %v", test.input, res, test.want)
+		}
+	}
+
+	if _, err := parseOrigin("yesterday", now); err == nil {
+		t.Errorf("parseOrigin(%q) should fail", "yesterday")
+	}
+}
+
+func TestAlertLog(t *testing.T) {
+	start := time.Date(2020, 5, 10, 18, 0, 0, 0, time.UTC)
+	down := alerting.Alert{URL: "https://shop.example.com", Time: start, Severity: alerting.SeverityCritical, Message: "Website https://shop.example.com is down"}
+	up := alerting.Alert{URL: "https://shop.example.com", Up: true, Time: start.Add(time.Minute), Severity: alerting.SeverityInfo, Message: "Website https://shop.example.com is up"}
+	flapping := alerting.Alert{URL: "https://api.example.com", Time: start.Add(2 * time.Minute), Severity: alerting.SeverityWarning, Message: "Website https://api.example.com is flapping"}
+
+	var log []logEntry
+	for _, e := range []logEntry{alertEntry(down), alertEntry(up), alertEntry(up), messageEntry(start.Add(3*time.Minute), "Config reloaded"), alertEntry(flapping)} {
+		log = appendEntry(log, e)
+	}
+	if len(log) != 4 {
+		t.Fatalf("Got %d entries, want 4: the second recovery alert is a duplicate", len(log))
+	}
+
+	tests := []struct {
+		filter string
+		until  time.Time
+		want   int
+	}{
+		{"", start.Add(time.Hour), 4},
+		{"severity:critical", start.Add(time.Hour), 1},
+		{"severity:info", start.Add(time.Hour), 2},
+		{"site:shop", start.Add(time.Hour), 2},
+		{"CONFIG", start.Add(time.Hour), 1},
+		{"site:api flapping", start.Add(time.Hour), 1},
+		{"", start.Add(30 * time.Second), 1},
+	}
+	for _, test := range tests {
+		if res := visibleEntries(log, test.filter, test.until); len(res) != test.want {
+			t.Errorf("Filter %q until %v: got %d entries, want %d", test.filter, test.until, len(res), test.want)
+		}
+	}
+
+	// the history stores the alerts to the millisecond, the subscription gets them to the nanosecond
+	live := alerting.Alert{URL: "https://api.example.com", Up: true, Time: start.Add(5*time.Minute + 1234567*time.Nanosecond), Severity: alerting.SeverityInfo}
+	stored := live
+	stored.Time = live.Time.Truncate(time.Millisecond)
+	if res := appendEntry(appendEntry(log, alertEntry(stored)), alertEntry(live)); len(res) != len(log)+1 {
+		t.Errorf("Got %d entries, want %d: the live alert is the stored one", len(res), len(log)+1)
+	}
+
+	for i := 0; i < alertLogSize+10; i++ {
+		log = appendEntry(log, messageEntry(start.Add(time.Duration(i)*time.Second), "message"))
+	}
+	if len(log) != alertLogSize {
+		t.Errorf("Got %d entries, want the last %d", len(log), alertLogSize)
+	}
+}
+
+func TestPlainCSV(t *testing.T) {
+	var b bytes.Buffer
+	p := &plainWriter{w: &b, format: FormatCSV}
+	at := time.Date(2020, 5, 10, 18, 0, 0, 0, time.UTC)
+
+	r := row{name: "https://shop.example.com", state: "up", stats: statsagent.WebsiteStats{Availability: 1, AvgResponseTime: 120 * time.Millisecond, StatusCodeCount: map[string]int{"200": 3, "404": 1}}}
+	if err := p.writeView(0, View{TimeFrame: 600}, at, []row{r}); err != nil {
+		t.Fatal(err)
+	}
+	down := alerting.Alert{URL: "https://shop.example.com", Time: at, Severity: alerting.SeverityCritical, Message: "Website https://shop.example.com is down, availability = 50%\n"}
+	if err := p.writeEntry(alertEntry(down)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"stats,2020-05-10T18:00:00Z,1,https://shop.example.com,up,1.0000,120.00,0.00,0.00,0.00,200:3 404:1,,",
+		"alert,2020-05-10T18:00:00Z,,https://shop.example.com,,,,,,,,critical,\"Website https://shop.example.com is down, availability = 50%\"",
+	}
+	if lines := strings.Split(strings.TrimSpace(b.String()), "\n"); strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Got\n%v\nwant\n%v", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	tests := []struct {
+		url, interval, status, persist string
+		want                           monitor.Website
+		wantPersist                    bool
+	}{
+		{" https://shop.example.com ", "30s", "200", "no", monitor.Website{URL: "https://shop.example.com", CheckInterval: 30}, false},
+		{"https://shop.example.com", "10", "204", "yes", monitor.Website{URL: "https://shop.example.com", CheckInterval: 10, ExpectedStatus: 204}, true},
+		{"https://shop.example.com", "2m", "", "", monitor.Website{URL: "https://shop.example.com", CheckInterval: 120}, false},
+	}
+	for _, test := range tests {
+		res, persist, err := parseForm(test.url, test.interval, test.status, test.persist)
+		if err != nil {
+			t.Errorf("parseForm(%q, %q, %q, %q) returned %v", test.url, test.interval, test.status, test.persist, err)
+			continue
+		}
+		if res.URL != test.want.URL || res.CheckInterval != test.want.CheckInterval || res.ExpectedStatus != test.want.ExpectedStatus || persist != test.wantPersist {
+			t.Errorf("parseForm(%q, %q, %q, %q) = %+v, %v, want %+v, %v", test.url, test.interval, test.status, test.persist, res, persist, test.want, test.wantPersist)
+		}
+	}
+
+	for _, fields := range [][4]string{
+		{"https://shop.example.com", "soon", "200", "no"},
+		{"https://shop.example.com", "1.5s", "200", "no"},
+		{"https://shop.example.com", "5s", "ok", "no"},
+		{"https://shop.example.com", "5s", "200", "maybe"},
+	} {
+		if _, _, err := parseForm(fields[0], fields[1], fields[2], fields[3]); err == nil {
+			t.Errorf("parseForm(%q) should fail", fields)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/database"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/secret"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+	"github.com/fatih/color"
+	"github.com/jroimartin/gocui"
+)
+
+// recentChecks is the number of checks listed in the detail pane
+const recentChecks = 20
+
+// refreshDetail loads the checks of a website over a timeframe and draws them in the detail pane, if it still shows this website
+func refreshDetail(g *gocui.Gui, ws monitor.Website, timeFrame int64) {
+	dataMu.Lock()
+	now, live := at(time.Now()), origin.IsZero()
+	dataMu.Unlock()
+
+	records, err := database.GetRecordsForURL(ws.URL, now, timeFrame)
+	state, _ := alerting.Status(ws.URL)
+	if err == nil && !live {
+		state, err = alerting.StatusAt(ws.URL, now)
+	}
+
+	g.Update(func(g *gocui.Gui) error {
+		dataMu.Lock()
+		open := detailURL == ws.URL
+		dataMu.Unlock()
+		v, verr := g.View("detail")
+		if !open || verr != nil {
+			return nil
+		}
+		v.Clear()
+		v.Title = " " + secret.Redact(ws.URL) + describeOrigin() + " (Esc to close) "
+		if err != nil {
+			fmt.Fprintln(v, secret.Redact(fmt.Sprintf("error while loading the checks: %v", err)))
+			return nil
+		}
+		// the chart takes the width of the pane, less the scale
+		width, _ := v.Size()
+		points := width - 11
+		if points < 1 {
+			points = 1
+		}
+		series := statsagent.GetSeriesForRecords(records, now, timeFrame, points)
+		writeDetail(v, ws, state, timeFrame, records, series, width)
+		return nil
+	})
+}
+
+// writeDetail describes the checks of a website: response time chart, status codes, errors, average phase durations and the last checks
+// the status codes of the checks take at most width characters
+func writeDetail(w io.Writer, ws monitor.Website, state string, timeFrame int64, records []request.ResponseLog, series []statsagent.SeriesPoint, width int) {
+	header := color.New(color.FgYellow, color.Bold)
+
+	fmt.Fprintf(w, "%v  state: %v", secret.Redact(ws.URL), state)
+	if ws.Group != "" {
+		fmt.Fprintf(w, "  group: %v", ws.Group)
+	}
+	fmt.Fprintf(w, "%v\n", formatTags(ws.Tags))
+	fmt.Fprintf(w, "%d checks over the last %vs, checked every %vs\n\n", len(records), timeFrame, ws.CheckInterval)
+	if len(records) == 0 {
+		return
+	}
+
+	header.Fprintln(w, "Response time")
+	writeChart(w, responseTimes(series), chartHeight, "ms")
+	fmt.Fprintf(w, "%8v │%v\n\n", "up", sparkline(availabilities(series), 1))
+
+	// status codes in the order of the checks, then their count
+	header.Fprintln(w, "Status codes")
+	counts := make(map[string]int)
+	for _, r := range records {
+		counts[r.StatusCode]++
+	}
+	fmt.Fprintln(w, checkMarks(records, width))
+	codes := make([]string, 0, len(counts))
+	for code, count := range counts {
+		if len(code) <= 3 {
+			codes = append(codes, fmt.Sprintf("%v:%d", code, count))
+		}
+	}
+	sort.Strings(codes)
+	fmt.Fprintf(w, "[%v]\n\n", strings.Join(codes, " "))
+
+	// the failed checks, by error
+	type failure struct {
+		message  string
+		count    int
+		lastSeen time.Time
+	}
+	failures := make(map[string]*failure)
+	for _, r := range records {
+		if r.Success {
+			continue
+		}
+		message := r.StatusCode
+		if len(message) <= 3 {
+			message = "HTTP " + message
+		}
+		if failures[message] == nil {
+			failures[message] = &failure{message: message}
+		}
+		failures[message].count++
+		if r.Timestamp.After(failures[message].lastSeen) {
+			failures[message].lastSeen = r.Timestamp
+		}
+	}
+	if len(failures) > 0 {
+		header.Fprintln(w, "Errors")
+		sorted := make([]*failure, 0, len(failures))
+		for _, f := range failures {
+			sorted = append(sorted, f)
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].lastSeen.After(sorted[j].lastSeen) })
+		for _, f := range sorted {
+			fmt.Fprintf(w, "%5d× %v, last at %v\n", f.count, secret.Redact(f.message), f.lastSeen.Format("15:04:05"))
+		}
+		fmt.Fprintln(w)
+	}
+
+	// the average duration of each phase, over the successful checks
+	var dns, connect, tls, ttfb, total time.Duration
+	successes := 0
+	for _, r := range records {
+		if r.Success {
+			successes++
+			dns, connect, tls, ttfb, total = dns+r.DNS, connect+r.Connect, tls+r.TLS, ttfb+r.TTFB, total+r.LoadTime
+		}
+	}
+	if successes > 0 {
+		n := time.Duration(successes)
+		header.Fprintln(w, "Average timings")
+		fmt.Fprintf(w, "dns %v  connect %v  tls %v  ttfb %v  total %v\n\n", formatPhase(dns/n), formatPhase(connect/n), formatPhase(tls/n), formatPhase(ttfb/n), formatPhase(total/n))
+	}
+
+	header.Fprintln(w, "Recent checks")
+	for i := len(records) - 1; i >= 0 && i >= len(records)-recentChecks; i-- {
+		r := records[i]
+		status := r.StatusCode
+		if r.Maintenance {
+			status += " (maintenance)"
+		}
+		if !r.Success {
+			fmt.Fprintf(w, "%v  %v\n", r.Timestamp.Format("15:04:05"), secret.Redact(status))
+			continue
+		}
+		fmt.Fprintf(w, "%v  %-4v dns %-8v connect %-8v tls %-8v ttfb %-8v total %v\n", r.Timestamp.Format("15:04:05"), status,
+			formatPhase(r.DNS), formatPhase(r.Connect), formatPhase(r.TLS), formatPhase(r.TTFB), formatPhase(r.LoadTime))
+	}
+}
+
+// checkMarks draws a mark per check, red when it failed, yellow during maintenance and green otherwise
+// when there are more checks than width, consecutive checks share a mark, which shows the worst of them
+func checkMarks(records []request.ResponseLog, width int) string {
+	if width < 1 {
+		width = 1
+	}
+	marks := len(records)
+	if marks > width {
+		marks = width
+	}
+
+	var res strings.Builder
+	for i := 0; i < marks; i++ {
+		failed, maintenance := false, false
+		for _, r := range records[i*len(records)/marks : (i+1)*len(records)/marks] {
+			failed = failed || (!r.Maintenance && !r.Success)
+			maintenance = maintenance || r.Maintenance
+		}
+		switch {
+		case failed:
+			res.WriteString(color.RedString("▮"))
+		case maintenance:
+			res.WriteString(color.YellowString("▮"))
+		default:
+			res.WriteString(color.GreenString("▮"))
+		}
+	}
+	return res.String()
+}
+
+// formatPhase rounds a duration to a readable precision
+func formatPhase(d time.Duration) string {
+	return d.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,206 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+	"golang.org/x/sync/errgroup"
+)
+
+// Formats of the plain output
+const (
+	FormatText = "text"
+	FormatCSV  = "csv"
+)
+
+// plainColumns is the header of the CSV output, the stats rows leave the alert columns empty and the other way round
+var plainColumns = []string{"type", "time", "view", "name", "state", "availability", "avgResponseMs", "maxResponseMs", "avgTtfbMs", "maxTtfbMs", "statusCodes", "severity", "message"}
+
+// plainWriter writes the refreshes of the views and the alerts as lines of text or CSV
+type plainWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+}
+
+// RunPlain prints each refresh of the views and each alert to w instead of drawing the dashboard,
+// so it can be followed in CI logs, with watch or in a tmux pane
+// format is FormatText or FormatCSV, new views can be sent through viewc
+func RunPlain(ctx context.Context, w io.Writer, format string, sites func() []monitor.Website, views []View, viewc <-chan []View, alertc chan string) error {
+	if format != FormatText && format != FormatCSV {
+		return fmt.Errorf("unknown output format %q, expected %v or %v", format, FormatText, FormatCSV)
+	}
+	p := &plainWriter{w: w, format: format}
+	if format == FormatCSV {
+		if err := p.writeCSV(plainColumns); err != nil {
+			return err
+		}
+	}
+
+	errg, gctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		return p.runViews(gctx, sites, views, viewc)
+	})
+	errg.Go(func() error {
+		return p.runAlerts(gctx, alertc)
+	})
+	if err := errg.Wait(); err != nil {
+		return fmt.Errorf("dashboard process error: %v", err)
+	}
+	return nil
+}
+
+// runViews prints the views following their update interval, and restarts them when new views are received
+func (p *plainWriter) runViews(ctx context.Context, sites func() []monitor.Website, views []View, viewc <-chan []View) error {
+	for {
+		errg, vctx := errgroup.WithContext(ctx)
+		vctx, cancel := context.WithCancel(vctx)
+		for index, view := range views {
+			index, view := index, view
+			errg.Go(func() error {
+				ticker := time.NewTicker(time.Duration(view.UpdateInterval) * time.Second)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-vctx.Done():
+						return nil
+					case t := <-ticker.C:
+						data, err := collect(view, sites(), t, true, sparkPoints)
+						if err != nil {
+							return err
+						}
+						if err := p.writeView(index, view, t, rows(data, view.Grouped)); err != nil {
+							return err
+						}
+					}
+				}
+			})
+		}
+
+		select {
+		case views = <-viewc:
+			cancel()
+			if err := errg.Wait(); err != nil {
+				return err
+			}
+		case <-vctx.Done():
+			cancel()
+			return errg.Wait()
+		}
+	}
+}
+
+// runAlerts prints the alerts stored by previous runs, then the new alerts and the messages sent through alertc
+// like in the alerts pane, the alerts both in the history and in the subscription are printed once
+func (p *plainWriter) runAlerts(ctx context.Context, alertc chan string) error {
+	alerts, unsubscribe := alerting.Subscribe(alertHistorySize)
+	defer unsubscribe()
+
+	history, err := alerting.History(alertHistorySize)
+	if err != nil {
+		return fmt.Errorf("error while loading the alert history: %v", err)
+	}
+	log := make([]logEntry, 0, len(history))
+	for _, alert := range history {
+		e := alertEntry(alert)
+		if logged(log, e) {
+			continue
+		}
+		log = appendEntry(log, e)
+		if err := p.writeEntry(e); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case alert := <-alerts:
+			e := alertEntry(alert)
+			if logged(log, e) {
+				continue
+			}
+			log = appendEntry(log, e)
+			if err := p.writeEntry(e); err != nil {
+				return err
+			}
+		case message := <-alertc:
+			if err := p.writeEntry(messageEntry(time.Now(), message)); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// writeView prints the rows of a view
+func (p *plainWriter) writeView(index int, view View, t time.Time, viewRows []row) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.format == FormatCSV {
+		w := csv.NewWriter(p.w)
+		for _, r := range viewRows {
+			s := r.stats
+			w.Write([]string{"stats", t.Format(time.RFC3339), fmt.Sprint(index + 1), r.name, r.state, fmt.Sprintf("%.4f", s.Availability),
+				formatMs(s.AvgResponseTime), formatMs(s.MaxResponseTime), formatMs(s.AvgTimeToFirstByte), formatMs(s.MaxTimeToFirstByte),
+				statsagent.FormatStatusCodes(s.StatusCodeCount), "", ""})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	name := "website"
+	if view.Grouped {
+		name = "group"
+	}
+	fmt.Fprintf(p.w, "== %v view %d: last %vs%v ==\n", t.Format("2006-01-02 15:04:05"), index+1, view.TimeFrame, formatTags(view.Tags))
+	fmt.Fprintf(p.w, "%-30v %-9v %12v %12v %12v %12v %12v  %-*v %v\n", name, "state", "availability", "avg rt", "max rt", "avg ttfb", "max ttfb", sparkPoints, "response time", "status codes")
+	for _, r := range viewRows {
+		s := r.stats
+		times := responseTimes(r.series)
+		fmt.Fprintf(p.w, "%-30v %-9v %11.2f%% %10vms %10vms %10vms %10vms  %-*v [%v]\n", r.name, r.state, 100*s.Availability,
+			formatMs(s.AvgResponseTime), formatMs(s.MaxResponseTime), formatMs(s.AvgTimeToFirstByte), formatMs(s.MaxTimeToFirstByte),
+			sparkPoints, sparkline(times, maxValue(times)), statsagent.FormatStatusCodes(s.StatusCodeCount))
+	}
+	_, err := fmt.Fprintln(p.w)
+	return err
+}
+
+// writeEntry prints an alert or a message, without colors
+func (p *plainWriter) writeEntry(e logEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	text := strings.TrimSpace(e.text)
+	if p.format == FormatCSV {
+		kind, url := "message", ""
+		if e.alert != nil {
+			kind, url = "alert", secret.Redact(e.alert.URL)
+		}
+		return p.writeCSV([]string{kind, e.time.Format(time.RFC3339), "", url, "", "", "", "", "", "", "", e.severity(), text})
+	}
+	_, err := fmt.Fprintf(p.w, "%v %v %v\n", e.time.Format("2006-01-02 15:04:05"), severityLabel(e.severity()), text)
+	return err
+}
+
+func (p *plainWriter) writeCSV(record []string) error {
+	w := csv.NewWriter(p.w)
+	w.Write(record)
+	w.Flush()
+	return w.Error()
+}
+
+// formatMs formats a duration in milliseconds with two decimals
+func formatMs(d time.Duration) string {
+	return fmt.Sprintf("%.2f", statsagent.Milliseconds(d))
+}
@@ -0,0 +1,114 @@
+package dashboard
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// The moment shown by the dashboard, guarded by dataMu
+var (
+	// origin is the end of the timeframes of the views, the zero time follows the current time
+	origin time.Time
+	// originChanged is closed when origin changes, so every view is updated right away
+	originChanged = make(chan struct{})
+)
+
+// timeLayouts are the layouts accepted when typing a time, the ones without a date are for the current day
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "15:04:05", "15:04"}
+
+// parseOrigin reads a typed time: a time in one of timeLayouts, local to the timezone of now,
+// a duration ago like "90m" or "-2h", or "now"
+func parseOrigin(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "now" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		if !strings.HasPrefix(layout, "2006") {
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unknown time %q, expected a time like 2020-05-10 14:30, 14:30, or a duration ago like 90m", s)
+}
+
+// at returns the time the stats are computed for, the caller must hold dataMu
+func at(now time.Time) time.Time {
+	if origin.IsZero() {
+		return now
+	}
+	return origin
+}
+
+// currentOrigin returns the time the dashboard shows, the current time when it's live
+func currentOrigin() time.Time {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	return at(time.Now())
+}
+
+// travelTo shows the dashboard at a past time, a zero or future time goes back to the current time
+// the views are updated by their goroutines, the alerts pane right away
+func travelTo(g *gocui.Gui, t time.Time) {
+	dataMu.Lock()
+	if t.After(time.Now()) {
+		t = time.Time{}
+	}
+	origin = t
+	close(originChanged)
+	originChanged = make(chan struct{})
+	dataMu.Unlock()
+	drawAlerts(g)
+}
+
+// travel moves the time shown by the dashboard
+func travel(g *gocui.Gui, d time.Duration) {
+	travelTo(g, currentOrigin().Add(d))
+}
+
+// openTimeInput opens the input line to type the time to show
+func openTimeInput(g *gocui.Gui) error {
+	v, err := openOverlay(g, "time")
+	if err != nil {
+		return err
+	}
+	v.Title = " Show the dashboard at: 2020-05-10 14:30, 14:30, 90m ago as 90m, or now (Enter to apply, Esc to cancel) "
+	v.Editable = true
+	g.Cursor = true
+	return nil
+}
+
+// closeTimeInput closes the input line, and moves to the typed time
+// an invalid time is reported in the title of the input line, which stays open
+func closeTimeInput(g *gocui.Gui, v *gocui.View, apply bool) error {
+	if apply {
+		t, err := parseOrigin(v.Buffer(), time.Now())
+		if err != nil {
+			v.Title = " " + err.Error() + " "
+			return nil
+		}
+		travelTo(g, t)
+	}
+	g.Cursor = false
+	return closeOverlay(g, "time")
+}
+
+// describeOrigin describes the time shown by the dashboard, for the titles of the panes
+func describeOrigin() string {
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	if origin.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" at %v [n for now]", origin.Format("2006-01-02 15:04:05"))
+}
@@ -19,6 +19,24 @@ type Database interface {
 	GetDatabaseName() string
 	AddResponseLog(responseLog request.ResponseLog) error
 	GetRangeRecords(span int) []client.Result
+	AddAlertEvent(event AlertEvent) error
+	GetAlertEvents(limit int) ([]AlertEvent, error)
+	GetAlertEventsSince(since time.Time) ([]AlertEvent, error)
+	GetLastAlertEvent(url string, before time.Time) (AlertEvent, bool, error)
+	GetDailyCounts(url string, from time.Time, to time.Time) ([]DailyCount, error)
+}
+
+// AlertEvent is the stored form of an alert, a change in the state of a website
+// SuppressedBy and Silenced are empty for the alerts stored before they were recorded
+type AlertEvent struct {
+	Timestamp    time.Time
+	URL          string
+	Up           bool
+	Availability float64
+	Severity     string
+	Message      string
+	SuppressedBy string
+	Silenced     bool
 }
 
 // Type is the database type
@@ -53,3 +71,59 @@ func GetRecordsForURL(url string, origin time.Time, timeframe int64) ([]request.
 	}
 	return res, nil
 }
+
+// DailyCount is the number of checks of a website during a day, by outcome
+type DailyCount struct {
+	Day                time.Time
+	Total              int64
+	Success            int64
+	Maintenance        int64
+	SuccessMaintenance int64
+}
+
+// GetDailyCounts counts the checks of a website per day, from the start of the day of "from" to "to"
+// days are in UTC
+func GetDailyCounts(url string, from time.Time, to time.Time) ([]DailyCount, error) {
+	res, err := dbName.GetDailyCounts(url, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error while counting the checks of %v:\n %v", url, err)
+	}
+	return res, nil
+}
+
+// WriteAlertEvent stores an alert in our database
+func WriteAlertEvent(event AlertEvent) error {
+	if err := dbName.AddAlertEvent(event); err != nil {
+		return fmt.Errorf("error while writing an alert to the database:\n %v", err)
+	}
+	return nil
+}
+
+// GetAlertEvents gets the last stored alerts, oldest first
+// a limit <= 0 returns all of them
+func GetAlertEvents(limit int) ([]AlertEvent, error) {
+	res, err := dbName.GetAlertEvents(limit)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading alerts from the database:\n %v", err)
+	}
+	return res, nil
+}
+
+// GetAlertEventsSince gets the alerts stored after a given time, oldest first
+func GetAlertEventsSince(since time.Time) ([]AlertEvent, error) {
+	res, err := dbName.GetAlertEventsSince(since)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading alerts from the database:\n %v", err)
+	}
+	return res, nil
+}
+
+// GetLastAlertEvent gets the last stored alert of a website, at or before a given time
+// the boolean is false if the website never had an alert
+func GetLastAlertEvent(url string, before time.Time) (AlertEvent, bool, error) {
+	res, ok, err := dbName.GetLastAlertEvent(url, before)
+	if err != nil {
+		return AlertEvent{}, false, fmt.Errorf("error while reading the last alert of %v from the database:\n %v", url, err)
+	}
+	return res, ok, nil
+}
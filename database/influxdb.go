@@ -1,8 +1,10 @@
 package database
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	str2duration "github.com/xhit/go-str2duration"
@@ -13,6 +15,9 @@ import (
 
 const (
 	layout string = "2006-01-02T15:04:05.000Z"
+
+	// alertsMeasurement stores the alert events of every website
+	alertsMeasurement string = "alerts"
 )
 
 type InfluxDb struct {
@@ -73,6 +78,10 @@ func (influxDb InfluxDb) AddRecord(responseLog request.ResponseLog) error {
 		"timeToFirstByte": responseLog.TTFB,
 		"StatusCode":      responseLog.StatusCode,
 		"Success":         responseLog.Success,
+		"Maintenance":     responseLog.Maintenance,
+		"dnsLookup":       responseLog.DNS,
+		"connect":         responseLog.Connect,
+		"tlsHandshake":    responseLog.TLS,
 	}
 
 	bps, err := client.NewBatchPoints(client.BatchPointsConfig{
@@ -107,7 +116,7 @@ func (influxDb InfluxDb) AddRecord(responseLog request.ResponseLog) error {
 // GetRecordsForURL sends a query to InfluxDB
 // to get records of a given URL, older than a given "origin" and restricted by a given timeframe
 func (influxDb InfluxDb) GetRecordsForURL(url string, origin time.Time, timeframe int64) ([]request.ResponseLog, error) {
-	q := fmt.Sprintf(`select * from "%s" WHERE time >= '%v' - %dm`, url, origin.Format(time.RFC3339), timeframe/60)
+	q := fmt.Sprintf(`select * from %s WHERE time >= '%v' - %ds AND time <= '%v'`, quoteIdentifier(url), origin.Format(time.RFC3339Nano), timeframe, origin.Format(time.RFC3339Nano))
 	res, err := queryDB(q, influxDb.DatabaseName)
 	if err != nil {
 		return nil, fmt.Errorf("error executing query %v", err)
@@ -120,29 +129,238 @@ func (influxDb InfluxDb) GetRecordsForURL(url string, origin time.Time, timefram
 		if len(result.Series) == 0 {
 			continue
 		}
+		columns := columnIndexes(result.Series[0].Columns)
 		for _, val := range result.Series[0].Values {
-			timestamp, err := time.Parse(time.RFC3339, val[0].(string))
+			timestamp, err := time.Parse(time.RFC3339, val[columns["time"]].(string))
 			if err != nil {
-				return nil, fmt.Errorf("error parsing time %v:\n %v", val[0], err)
+				return nil, fmt.Errorf("error parsing time %v:\n %v", val[columns["time"]], err)
 			}
-			statusCode := val[1].(string)
-			success := val[2].(bool)
-			url := val[3].(string)
-			responseTime, err := s2dParser.Str2Duration(val[4].(string))
+			statusCode := val[columns["StatusCode"]].(string)
+			success := val[columns["Success"]].(bool)
+			url := val[columns["requestId"]].(string)
+			responseTime, err := s2dParser.Str2Duration(val[columns["responseTime"]].(string))
 			if err != nil {
-				return nil, fmt.Errorf("error parsing response time %v:\n %v", val[4], err)
+				return nil, fmt.Errorf("error parsing response time %v:\n %v", val[columns["responseTime"]], err)
 			}
-			timeToFirstByte, err := s2dParser.Str2Duration(val[5].(string))
+			timeToFirstByte, err := s2dParser.Str2Duration(val[columns["timeToFirstByte"]].(string))
 			if err != nil {
-				return nil, fmt.Errorf("error parsing time to first byte %v:\n %v", val[5], err)
+				return nil, fmt.Errorf("error parsing time to first byte %v:\n %v", val[columns["timeToFirstByte"]], err)
+			}
+			// records written before maintenance flags existed have no value
+			maintenance := false
+			if i, ok := columns["Maintenance"]; ok && val[i] != nil {
+				maintenance = val[i].(bool)
+			}
+			item := request.ResponseLog{Timestamp: timestamp, StatusCode: statusCode, URL: url, TTFB: timeToFirstByte, LoadTime: responseTime, Success: success, Maintenance: maintenance}
+			// so do the records written before the phases of the requests were stored
+			for column, phase := range map[string]*time.Duration{"dnsLookup": &item.DNS, "connect": &item.Connect, "tlsHandshake": &item.TLS} {
+				i, ok := columns[column]
+				if !ok || val[i] == nil {
+					continue
+				}
+				if *phase, err = s2dParser.Str2Duration(val[i].(string)); err != nil {
+					return nil, fmt.Errorf("error parsing %v %v:\n %v", column, val[i], err)
+				}
 			}
-			item := request.ResponseLog{Timestamp: timestamp, StatusCode: statusCode, URL: url, TTFB: timeToFirstByte, LoadTime: responseTime, Success: success}
 			records = append(records, item)
 		}
 	}
 	return records, nil
 }
 
+// GetDailyCounts sends one query per outcome to InfluxDB, to count the checks of a given URL per day
+func (influxDb InfluxDb) GetDailyCounts(url string, from time.Time, to time.Time) ([]DailyCount, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	conditions := []string{"", ` AND "Success" = true`, ` AND "Maintenance" = true`, ` AND "Success" = true AND "Maintenance" = true`}
+
+	queries := make([]string, 0, len(conditions))
+	for _, condition := range conditions {
+		queries = append(queries, fmt.Sprintf(`select count("StatusCode") from %s WHERE time >= '%v' AND time <= '%v'%s GROUP BY time(1d) fill(0)`,
+			quoteIdentifier(url), from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano), condition))
+	}
+	res, err := queryDB(strings.Join(queries, "; "), influxDb.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query %v", err)
+	}
+
+	days := make([]DailyCount, 0)
+	index := make(map[time.Time]int)
+	for i, result := range res {
+		if len(result.Series) == 0 {
+			continue
+		}
+		for _, val := range result.Series[0].Values {
+			day, err := time.Parse(time.RFC3339, val[0].(string))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing time %v:\n %v", val[0], err)
+			}
+			count, err := parseFloat(val[1])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing count %v:\n %v", val[1], err)
+			}
+
+			j, ok := index[day]
+			if !ok {
+				j = len(days)
+				index[day] = j
+				days = append(days, DailyCount{Day: day})
+			}
+			switch i {
+			case 0:
+				days[j].Total = int64(count)
+			case 1:
+				days[j].Success = int64(count)
+			case 2:
+				days[j].Maintenance = int64(count)
+			case 3:
+				days[j].SuccessMaintenance = int64(count)
+			}
+		}
+	}
+	return days, nil
+}
+
+// AddAlertEvent adds an alert event to InfluxDB
+func (influxDb InfluxDb) AddAlertEvent(event AlertEvent) error {
+	tags := map[string]string{
+		"url": event.URL,
+	}
+	fields := map[string]interface{}{
+		"up":           event.Up,
+		"availability": event.Availability,
+		"severity":     event.Severity,
+		"message":      event.Message,
+		"silenced":     event.Silenced,
+	}
+	if event.SuppressedBy != "" {
+		fields["suppressedBy"] = event.SuppressedBy
+	}
+
+	bps, err := client.NewBatchPoints(client.BatchPointsConfig{
+		Database:  influxDb.DatabaseName,
+		Precision: "ms",
+	})
+	if err != nil {
+		return err
+	}
+
+	point, err := client.NewPoint(alertsMeasurement, tags, fields, event.Timestamp)
+	if err != nil {
+		return err
+	}
+	bps.AddPoint(point)
+
+	return influxDBcon.Write(bps)
+}
+
+// GetAlertEvents sends a query to InfluxDB to get the last alert events, oldest first
+func (influxDb InfluxDb) GetAlertEvents(limit int) ([]AlertEvent, error) {
+	q := fmt.Sprintf(`select * from "%s" order by time desc`, alertsMeasurement)
+	if limit > 0 {
+		q = fmt.Sprintf("%s limit %d", q, limit)
+	}
+	events, err := queryAlertEvents(q, influxDb.DatabaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
+
+// GetAlertEventsSince sends a query to InfluxDB to get the alert events after a given time, oldest first
+func (influxDb InfluxDb) GetAlertEventsSince(since time.Time) ([]AlertEvent, error) {
+	q := fmt.Sprintf(`select * from "%s" where time > '%v' order by time asc`, alertsMeasurement, since.Format(time.RFC3339Nano))
+	return queryAlertEvents(q, influxDb.DatabaseName)
+}
+
+// GetLastAlertEvent sends a query to InfluxDB to get the last alert event of a given URL, at or before a given time
+func (influxDb InfluxDb) GetLastAlertEvent(url string, before time.Time) (AlertEvent, bool, error) {
+	q := fmt.Sprintf(`select * from "%s" where "url" = %s AND time <= '%v' order by time desc limit 1`, alertsMeasurement, quoteString(url), before.Format(time.RFC3339Nano))
+	events, err := queryAlertEvents(q, influxDb.DatabaseName)
+	if err != nil {
+		return AlertEvent{}, false, err
+	}
+	if len(events) == 0 {
+		return AlertEvent{}, false, nil
+	}
+	return events[0], true, nil
+}
+
+func queryAlertEvents(q string, databaseName string) ([]AlertEvent, error) {
+	res, err := queryDB(q, databaseName)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query %v", err)
+	}
+
+	events := make([]AlertEvent, 0)
+	for _, result := range res {
+		if len(result.Series) == 0 {
+			continue
+		}
+		columns := columnIndexes(result.Series[0].Columns)
+		for _, val := range result.Series[0].Values {
+			timestamp, err := time.Parse(time.RFC3339, val[columns["time"]].(string))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing time %v:\n %v", val[columns["time"]], err)
+			}
+			availability, err := parseFloat(val[columns["availability"]])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing availability %v:\n %v", val[columns["availability"]], err)
+			}
+			item := AlertEvent{
+				Timestamp:    timestamp,
+				URL:          val[columns["url"]].(string),
+				Up:           val[columns["up"]].(bool),
+				Availability: availability,
+				Severity:     val[columns["severity"]].(string),
+				Message:      val[columns["message"]].(string),
+			}
+			// the columns are missing, or null, for the alerts stored before they were recorded
+			if i, ok := columns["suppressedBy"]; ok {
+				item.SuppressedBy, _ = val[i].(string)
+			}
+			if i, ok := columns["silenced"]; ok {
+				item.Silenced, _ = val[i].(bool)
+			}
+			events = append(events, item)
+		}
+	}
+	return events, nil
+}
+
+// quoteIdentifier quotes a measurement name for InfluxQL, the measurements of the checks are named after the URLs
+func quoteIdentifier(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// quoteString quotes a string literal for InfluxQL
+func quoteString(value string) string {
+	return `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + `'`
+}
+
+// columnIndexes maps the columns of a serie to their position
+func columnIndexes(columns []string) map[string]int {
+	indexes := make(map[string]int)
+	for i, column := range columns {
+		indexes[column] = i
+	}
+	return indexes
+}
+
+// parseFloat reads a float field, the client decodes numbers as json.Number
+func parseFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("unexpected type %T", v)
+}
+
 func createDatabase(databaseName string) error {
 
 	_, err := queryDB(fmt.Sprintf("create database %s", databaseName), "")
@@ -0,0 +1,87 @@
+package eventlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/secret"
+)
+
+// Event types
+const (
+	TypeCheck   string = "check"
+	TypeAlert   string = "alert"
+	TypeMessage string = "message"
+	TypeError   string = "error"
+)
+
+// Fields are the content of an event, besides its time and type
+type Fields map[string]interface{}
+
+var (
+	output io.Writer
+	mu     sync.Mutex
+)
+
+// SetOutput sets where the events are written, one JSON object per line
+// events are dropped while no output is set
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	output = w
+}
+
+// Write writes an event of the given type, fields can't override the time and the type of the event
+func Write(eventType string, t time.Time, fields Fields) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if output == nil {
+		return nil
+	}
+
+	event := make(Fields, len(fields)+2)
+	for k, v := range fields {
+		event[k] = v
+	}
+	event["time"] = t
+	event["type"] = eventType
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding a %v event: %v", eventType, err)
+	}
+	line = []byte(secret.Redact(string(line)))
+	if _, err := output.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing a %v event: %v", eventType, err)
+	}
+	return nil
+}
+
+// Check writes the result of a check
+func Check(log request.ResponseLog) error {
+	fields := Fields{
+		"url":            log.URL,
+		"statusCode":     log.StatusCode,
+		"success":        log.Success,
+		"responseTimeMs": float64(log.LoadTime) / float64(time.Millisecond),
+		"ttfbMs":         float64(log.TTFB) / float64(time.Millisecond),
+	}
+	if log.Maintenance {
+		fields["maintenance"] = true
+	}
+	return Write(TypeCheck, log.Timestamp, fields)
+}
+
+// Message writes an informative message, like the outcome of a config reload
+func Message(message string) error {
+	return Write(TypeMessage, time.Now(), Fields{"message": message})
+}
+
+// Error writes an error
+func Error(err error) error {
+	return Write(TypeError, time.Now(), Fields{"error": err.Error()})
+}
@@ -0,0 +1,37 @@
+package eventlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/request"
+)
+
+func TestCheck(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(nil)
+
+	log := request.ResponseLog{Timestamp: time.Now(), URL: "https://a.com", StatusCode: "200", LoadTime: 1500 * time.Microsecond, Success: true}
+	if err := Check(log); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Message("hello"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Got %q, want one line per event", buf.String())
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event["type"] != TypeCheck || event["url"] != "https://a.com" || event["responseTimeMs"] != 1.5 || event["success"] != true {
+		t.Errorf("Got %v, want a check event for https://a.com", event)
+	}
+}
@@ -3,10 +3,12 @@ module github.com/ayoubed/datadog-home-project
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/fatih/color v1.9.0
 	github.com/influxdata/influxdb v1.8.0
 	github.com/jroimartin/gocui v0.4.0
 	github.com/nsf/termbox-go v0.0.0-20200418040025-38ba6e5628f1 // indirect
 	github.com/xhit/go-str2duration v1.0.1
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e
+	gopkg.in/yaml.v3 v3.0.1
 )
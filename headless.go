@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/dashboard"
+	"github.com/ayoubed/datadog-home-project/eventlog"
+)
+
+// alertEventsBuffer is the number of alerts waiting to be written to the event log
+const alertEventsBuffer = 100
+
+// openEvents opens the event log, "-" is the standard output, files are appended to
+func openEvents(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return os.Stdout, nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the event log: %v", err)
+	}
+	return f, nil
+}
+
+// logAlerts writes the alerts to the event log until the context is done
+func logAlerts(ctx context.Context, alerts <-chan alerting.Alert) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case alert := <-alerts:
+			fields := eventlog.Fields{
+				"url":          alert.URL,
+				"up":           alert.Up,
+				"availability": alert.Availability,
+				"severity":     alert.Severity,
+				"message":      alert.Message,
+			}
+			if alert.Silenced {
+				fields["silenced"] = true
+			}
+			if alert.SuppressedBy != "" {
+				fields["suppressedBy"] = alert.SuppressedBy
+			}
+			if len(alert.Affected) > 0 {
+				fields["affected"] = alert.Affected
+			}
+			if err := eventlog.Write(eventlog.TypeAlert, alert.Time, fields); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// drainDashboard stands for the dashboard in headless mode
+// the alerts and the reload reports it would show are already in the event log
+func drainDashboard(ctx context.Context, alertc <-chan string, viewc <-chan []dashboard.View) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-alertc:
+		case <-viewc:
+		}
+	}
+}
+
+// waitForSignal stops the program on SIGTERM or SIGINT
+func waitForSignal(ctx context.Context, done context.CancelFunc) error {
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(sigc)
+
+	select {
+	case <-ctx.Done():
+	case sig := <-sigc:
+		eventlog.Message(fmt.Sprintf("Received %v, shutting down", sig))
+		done()
+	}
+	return nil
+}
@@ -1,102 +1,74 @@
 package main
 
 import (
-	"context"
-	"encoding/json"
-	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"strings"
 
-	"github.com/ayoubed/datadog-home-project/alerting"
-	"github.com/ayoubed/datadog-home-project/dashboard"
+	"github.com/ayoubed/datadog-home-project/config"
 	"github.com/ayoubed/datadog-home-project/database"
-	"github.com/ayoubed/datadog-home-project/monitor"
-	"github.com/ayoubed/datadog-home-project/request"
-	"golang.org/x/sync/errgroup"
+	"github.com/ayoubed/datadog-home-project/secret"
 )
 
-// Config struct containing websites config(url, check interval), database data(host, dbaname, username, password)
-type Config struct {
-	Websites  []monitor.Website    `json:"websites"`
-	Database  database.Type        `json:"database"`
-	Dashboard []dashboard.View     `json:"dashboard"`
-	Alert     alerting.AlertConfig `json:"alerting"`
+// defaultConfig is the config file used when -config is not given
+const defaultConfig = "data/config.json"
+
+// command is a subcommand of the binary, it gets the arguments following its name
+type command struct {
+	run         func(args []string) error
+	description string
 }
 
-func main() {
-	var configFile = flag.String("config", "data/config.json", "JSON config file")
-	flag.Parse()
+var commands = map[string]command{
+	"run":      {runCommand, "monitor the websites of the config (default)"},
+	"check":    {checkCommand, "probe a URL once and print the timing breakdown"},
+	"validate": {validateCommand, "check a config file"},
+	"report":   {reportCommand, "print the stats of the websites for a time range"},
+	"export":   {exportCommand, "dump the stored checks or alerts as JSON or CSV"},
+	"ci":       {ciCommand, "check every website a few times and fail if one is down, without a database"},
+}
 
-	config, err := getConfig(*configFile)
+var commandOrder = []string{"run", "check", "validate", "report", "export", "ci"}
 
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+func main() {
+	// without a subcommand, the flags are the ones of run
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	if err := database.Set(config.Database); err != nil {
-		fmt.Fprintf(os.Stderr, "Error setting up the database: %v\n", err)
-		os.Exit(1)
+	if name == "help" {
+		usage()
+		return
 	}
-
-	websiteList := []string{}
-	websiteMap := make(map[string]int64)
-	for _, ws := range config.Websites {
-		websiteList = append(websiteList, ws.URL)
-		websiteMap[ws.URL] = int64(ws.CheckInterval)
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		usage()
+		os.Exit(2)
 	}
-
-	ctx, done := context.WithCancel(context.Background())
-	g, gctx := errgroup.WithContext(ctx)
-
-	logc := make(chan request.ResponseLog)
-	alertc := make(chan string)
-	defer close(logc)
-	defer close(alertc)
-
-	g.Go(func() error {
-		return dashboard.Run(gctx, websiteList, config.Dashboard, alertc, done)
-	})
-	g.Go(func() error {
-		return alerting.Run(gctx, alertc, websiteMap, config.Alert)
-	})
-
-	g.Go(func() error {
-		return monitor.ProcessLogs(gctx, logc)
-	})
-
-	for _, ws := range config.Websites {
-		ws := ws
-		g.Go(func() error {
-			return monitor.StartWebsiteMonitor(gctx, ws, logc)
-		})
-	}
-
-	if err := g.Wait(); err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", secret.Redact(err.Error()))
 		os.Exit(1)
 	}
-
 }
 
-func getConfig(filepath string) (Config, error) {
-	configFile, err := os.Open(filepath)
-	if err != nil {
-		return Config{}, fmt.Errorf("%v", err)
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %v <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %-10v%v\n", name, commands[name].description)
 	}
-	defer configFile.Close()
+	fmt.Fprintf(os.Stderr, "\nRun %v <command> -h for the flags of a command\n", os.Args[0])
+}
 
-	configByteContent, err := ioutil.ReadAll(configFile)
+// loadWithDatabase loads a config file and connects to its database
+func loadWithDatabase(path string) (config.Config, error) {
+	cfg, err := config.Load(path)
 	if err != nil {
-		return Config{}, err
+		return config.Config{}, fmt.Errorf("error loading config: %v", err)
 	}
-
-	var config Config
-
-	if err := json.Unmarshal(configByteContent, &config); err != nil {
-		return Config{}, err
+	if err := database.Set(cfg.Database); err != nil {
+		return config.Config{}, fmt.Errorf("error setting up the database: %v", err)
 	}
-
-	return config, nil
+	return cfg, nil
 }
@@ -0,0 +1,172 @@
+package maintenance
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Config lists the planned periods during which the alerts of some websites are muted
+type Config struct {
+	Silences []Silence `json:"silences"`
+	Windows  []Window  `json:"windows"`
+}
+
+// Silence mutes the alerts of the websites matching URL (a regular expression) between Start and End
+// a zero Start means the silence is active right away
+type Silence struct {
+	URL     string    `json:"url"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Comment string    `json:"comment"`
+}
+
+// Window is a recurring maintenance window for the websites matching URL (a regular expression)
+// Schedule is a cron expression (minute hour day-of-month month day-of-week) giving the start of the window,
+// which lasts Duration seconds
+type Window struct {
+	URL      string `json:"url"`
+	Schedule string `json:"schedule"`
+	Duration int64  `json:"duration"`
+	Comment  string `json:"comment"`
+}
+
+type silence struct {
+	Silence
+	url *regexp.Regexp
+	// added is set for silences added at runtime, they survive config reloads
+	added bool
+}
+
+type window struct {
+	Window
+	url      *regexp.Regexp
+	schedule schedule
+}
+
+var (
+	silences []silence
+	windows  []window
+	mu       sync.RWMutex
+)
+
+// Set replaces the silences and maintenance windows with the ones of the config
+// the current ones are kept if the config is invalid, silences added at runtime are always kept
+func Set(config Config) error {
+	newSilences, newWindows, err := compile(config)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, existing := range silences {
+		if existing.added {
+			newSilences = append(newSilences, existing)
+		}
+	}
+	silences = newSilences
+	windows = newWindows
+	return nil
+}
+
+// Validate checks the silences and maintenance windows of the config
+func Validate(config Config) error {
+	_, _, err := compile(config)
+	return err
+}
+
+func compile(config Config) ([]silence, []window, error) {
+	newSilences := make([]silence, 0, len(config.Silences))
+	for _, s := range config.Silences {
+		compiled, err := compileSilence(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		newSilences = append(newSilences, compiled)
+	}
+
+	newWindows := make([]window, 0, len(config.Windows))
+	for _, w := range config.Windows {
+		re, err := regexp.Compile(w.URL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("maintenance window %q has an invalid url pattern: %v", w.Schedule, err)
+		}
+		sched, err := parseSchedule(w.Schedule)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid maintenance window: %v", err)
+		}
+		if w.Duration <= 0 {
+			return nil, nil, fmt.Errorf("maintenance window %q should have a positive duration", w.Schedule)
+		}
+		newWindows = append(newWindows, window{Window: w, url: re, schedule: sched})
+	}
+	return newSilences, newWindows, nil
+}
+
+// AddSilence adds a one-off silence, expired silences are dropped along the way
+func AddSilence(s Silence) error {
+	compiled, err := compileSilence(s)
+	if err != nil {
+		return err
+	}
+	compiled.added = true
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	kept := make([]silence, 0, len(silences)+1)
+	for _, existing := range silences {
+		if existing.End.After(now) {
+			kept = append(kept, existing)
+		}
+	}
+	silences = append(kept, compiled)
+	return nil
+}
+
+// Silences returns the silences that did not expire yet
+func Silences() []Silence {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	now := time.Now()
+	res := make([]Silence, 0)
+	for _, s := range silences {
+		if s.End.After(now) {
+			res = append(res, s.Silence)
+		}
+	}
+	return res
+}
+
+// Active tells if a website is in a silence or a maintenance window at the given time
+func Active(url string, t time.Time) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, s := range silences {
+		if !t.Before(s.Start) && t.Before(s.End) && s.url.MatchString(url) {
+			return true
+		}
+	}
+	for _, w := range windows {
+		if w.url.MatchString(url) && w.schedule.activeAt(t, time.Duration(w.Duration)*time.Second) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileSilence(s Silence) (silence, error) {
+	re, err := regexp.Compile(s.URL)
+	if err != nil {
+		return silence{}, fmt.Errorf("silence %q has an invalid url pattern: %v", s.URL, err)
+	}
+	if s.End.IsZero() {
+		return silence{}, fmt.Errorf("silence %q has no end", s.URL)
+	}
+	return silence{Silence: s, url: re}, nil
+}
@@ -0,0 +1,144 @@
+package maintenance
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron expression: minute hour day-of-month month day-of-week
+type schedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domAny and dowAny are set when the field is "*",
+	// when both days are restricted a time matches if either of them does, like in cron
+	domAny, dowAny bool
+}
+
+var fieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+func parseSchedule(expr string) (schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return schedule{}, fmt.Errorf("schedule %q should have 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+
+	var parsed [5]map[int]bool
+	for i, field := range fields {
+		values, err := parseField(field, fieldBounds[i][0], fieldBounds[i][1])
+		if err != nil {
+			return schedule{}, fmt.Errorf("schedule %q: %v", expr, err)
+		}
+		parsed[i] = values
+	}
+	// sunday is either 0 or 7
+	if parsed[4][7] {
+		parsed[4][0] = true
+	}
+
+	return schedule{
+		minute: parsed[0],
+		hour:   parsed[1],
+		dom:    parsed[2],
+		month:  parsed[3],
+		dow:    parsed[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseField parses a comma separated list of "*", "n", "a-b", each with an optional "/step"
+func parseField(field string, min int, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = s
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			v, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			from, to = v, v
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid range %q", part)
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of the range %d-%d", part, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// matchesDay tells if the schedule fires during the day of t
+func (s schedule) matchesDay(t time.Time) bool {
+	if !s.month[int(t.Month())] {
+		return false
+	}
+	domMatch := s.dom[t.Day()]
+	dowMatch := s.dow[int(t.Weekday())]
+	if s.domAny || s.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// last returns the last time the schedule fired at or before t, if it's after since
+// it goes back a day, then an hour, then a minute at a time, skipping the days and hours the schedule doesn't fire
+func (s schedule) last(t time.Time, since time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	for day := t; ; day = day.AddDate(0, 0, -1) {
+		day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, t.Location())
+		if day.AddDate(0, 0, 1).Before(since) {
+			return time.Time{}, false
+		}
+		if !s.matchesDay(day) {
+			continue
+		}
+
+		sameDay := day.Year() == t.Year() && day.YearDay() == t.YearDay()
+		lastHour := 23
+		if sameDay {
+			lastHour = t.Hour()
+		}
+		for hour := lastHour; hour >= 0; hour-- {
+			if !s.hour[hour] {
+				continue
+			}
+			lastMinute := 59
+			if sameDay && hour == t.Hour() {
+				lastMinute = t.Minute()
+			}
+			for minute := lastMinute; minute >= 0; minute-- {
+				if s.minute[minute] {
+					fired := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, t.Location())
+					return fired, fired.After(since)
+				}
+			}
+		}
+	}
+}
+
+// activeAt tells if a window of the given duration started by the schedule contains t
+func (s schedule) activeAt(t time.Time, duration time.Duration) bool {
+	_, ok := s.last(t, t.Add(-duration))
+	return ok
+}
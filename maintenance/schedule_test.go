@@ -0,0 +1,78 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	// sunday 2020-05-03
+	sunday := time.Date(2020, time.May, 3, 2, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		duration time.Duration
+		at       time.Time
+		expected bool
+	}{
+		{"start of the window", "0 2 * * 0", time.Hour, sunday, true},
+		{"inside the window", "0 2 * * 0", time.Hour, sunday.Add(59 * time.Minute), true},
+		{"end of the window", "0 2 * * 0", time.Hour, sunday.Add(time.Hour), false},
+		{"before the window", "0 2 * * 0", time.Hour, sunday.Add(-time.Minute), false},
+		{"sunday as 7", "0 2 * * 7", time.Hour, sunday.Add(time.Minute), true},
+		{"other day", "0 2 * * 1-5", time.Hour, sunday, false},
+		{"steps", "*/15 * * * *", time.Minute, sunday.Add(45 * time.Minute), true},
+		{"lists", "10,20 * * * *", time.Minute, sunday.Add(15 * time.Minute), false},
+		{"day of month or day of week", "0 2 1 * 0", time.Hour, sunday, true},
+		{"one day window started the day before", "0 22 * * 6", 24 * time.Hour, sunday, true},
+		{"one day window over", "0 22 * * 5", 24 * time.Hour, sunday, false},
+		{"later the same day", "45 2 * * 0", 24 * time.Hour, sunday, false},
+		{"earlier hour", "30 1 * * *", time.Hour, sunday.Add(10 * time.Minute), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSchedule(tt.schedule)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := s.activeAt(tt.at, tt.duration); got != tt.expected {
+				t.Errorf("Got %v, want %v", got, tt.expected)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"* * * *", "60 * * * *", "* * * * mon", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := parseSchedule(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestScheduleLast(t *testing.T) {
+	// the minute by minute search activeAt replaced
+	walk := func(s schedule, at time.Time, duration time.Duration) bool {
+		for start := at.Truncate(time.Minute); at.Sub(start) < duration; start = start.Add(-time.Minute) {
+			if s.matchesDay(start) && s.hour[start.Hour()] && s.minute[start.Minute()] {
+				return true
+			}
+		}
+		return false
+	}
+
+	monday := time.Date(2020, time.May, 4, 0, 0, 0, 0, time.UTC)
+	for _, expr := range []string{"0 2 * * 0", "*/20 9-17 * * 1-5", "30 23 1,15 * *", "0 0 29 2 *", "15 4 1 * 3"} {
+		s, err := parseSchedule(expr)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for at := monday; at.Before(monday.AddDate(0, 0, 8)); at = at.Add(37 * time.Minute) {
+			for _, duration := range []time.Duration{time.Minute, 90 * time.Minute, 26 * time.Hour} {
+				if got, want := s.activeAt(at, duration), walk(s, at, duration); got != want {
+					t.Errorf("%q at %v for %v: got %v, want %v", expr, at, duration, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/secret"
+)
+
+// latencyBuckets are the upper bounds of the latency histograms, in seconds
+var latencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// check outcomes
+const (
+	outcomeSuccess     string = "success"
+	outcomeFailure     string = "failure"
+	outcomeMaintenance string = "maintenance"
+)
+
+// siteMetrics are the metrics of a website, updated on every check
+type siteMetrics struct {
+	up             bool
+	lastStatusCode int
+	responseTime   histogram
+	ttfb           histogram
+	checks         map[string]int64
+	certExpiry     time.Time
+}
+
+var (
+	mu             sync.Mutex
+	sites          map[string]*siteMetrics = make(map[string]*siteMetrics)
+	dbWriteLatency histogram               = newHistogram()
+	dbWriteErrors  int64
+	queueDepth     func() int
+)
+
+// ObserveCheck updates the metrics of a website with the result of a check
+func ObserveCheck(log request.ResponseLog) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	site, ok := sites[log.URL]
+	if !ok {
+		site = &siteMetrics{responseTime: newHistogram(), ttfb: newHistogram(), checks: make(map[string]int64)}
+		sites[log.URL] = site
+	}
+
+	site.up = log.Success
+	// timeouts and connection errors have no status code
+	site.lastStatusCode, _ = strconv.Atoi(log.StatusCode)
+	switch {
+	case log.Maintenance:
+		site.checks[outcomeMaintenance]++
+	case log.Success:
+		site.checks[outcomeSuccess]++
+	default:
+		site.checks[outcomeFailure]++
+	}
+	if log.Success {
+		site.responseTime.observe(log.LoadTime.Seconds())
+		site.ttfb.observe(log.TTFB.Seconds())
+	}
+	if !log.CertExpiry.IsZero() {
+		site.certExpiry = log.CertExpiry
+	}
+}
+
+// ObserveDBWrite records the latency and the outcome of a database write
+func ObserveDBWrite(d time.Duration, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dbWriteLatency.observe(d.Seconds())
+	if err != nil {
+		dbWriteErrors++
+	}
+}
+
+// SetQueueDepth sets the function giving the number of checks waiting to be processed
+func SetQueueDepth(depth func() int) {
+	mu.Lock()
+	defer mu.Unlock()
+	queueDepth = depth
+}
+
+// Retain drops the metrics of the websites that are not in urls
+func Retain(urls []string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	wanted := make(map[string]bool)
+	for _, url := range urls {
+		wanted[url] = true
+	}
+	for url := range sites {
+		if !wanted[url] {
+			delete(sites, url)
+		}
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(render())
+	})
+}
+
+func render() []byte {
+	mu.Lock()
+	defer mu.Unlock()
+
+	urls := make([]string, 0, len(sites))
+	for url := range sites {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	var buf bytes.Buffer
+
+	header(&buf, "website_up", "gauge", "Whether the last check of the website succeeded.")
+	for _, url := range urls {
+		fmt.Fprintf(&buf, "website_up{%s} %v\n", label("url", url), boolValue(sites[url].up))
+	}
+
+	header(&buf, "website_last_status_code", "gauge", "HTTP status code of the last check, 0 when there was no response.")
+	for _, url := range urls {
+		fmt.Fprintf(&buf, "website_last_status_code{%s} %d\n", label("url", url), sites[url].lastStatusCode)
+	}
+
+	header(&buf, "website_checks_total", "counter", "Number of checks of the website by outcome.")
+	for _, url := range urls {
+		for _, outcome := range []string{outcomeSuccess, outcomeFailure, outcomeMaintenance} {
+			fmt.Fprintf(&buf, "website_checks_total{%s,%s} %d\n", label("url", url), label("outcome", outcome), sites[url].checks[outcome])
+		}
+	}
+
+	header(&buf, "website_response_time_seconds", "histogram", "Response time of the successful checks.")
+	for _, url := range urls {
+		sites[url].responseTime.write(&buf, "website_response_time_seconds", label("url", url))
+	}
+
+	header(&buf, "website_ttfb_seconds", "histogram", "Time to first byte of the successful checks.")
+	for _, url := range urls {
+		sites[url].ttfb.write(&buf, "website_ttfb_seconds", label("url", url))
+	}
+
+	header(&buf, "website_certificate_expiry_timestamp_seconds", "gauge", "Expiry date of the TLS certificate of the website.")
+	for _, url := range urls {
+		if !sites[url].certExpiry.IsZero() {
+			fmt.Fprintf(&buf, "website_certificate_expiry_timestamp_seconds{%s} %d\n", label("url", url), sites[url].certExpiry.Unix())
+		}
+	}
+
+	if queueDepth != nil {
+		header(&buf, "monitor_check_queue_depth", "gauge", "Number of checks waiting to be written to the database.")
+		fmt.Fprintf(&buf, "monitor_check_queue_depth %d\n", queueDepth())
+	}
+
+	header(&buf, "monitor_db_write_duration_seconds", "histogram", "Latency of the database writes.")
+	dbWriteLatency.write(&buf, "monitor_db_write_duration_seconds", "")
+
+	header(&buf, "monitor_db_write_errors_total", "counter", "Number of failed database writes.")
+	fmt.Fprintf(&buf, "monitor_db_write_errors_total %d\n", dbWriteErrors)
+
+	return buf.Bytes()
+}
+
+func header(buf *bytes.Buffer, name string, kind string, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labelEscaper escapes label values like Prometheus does, other characters are kept as they are
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label renders a label and its value, with the secrets of the value redacted
+func label(name string, value string) string {
+	return name + `="` + labelEscaper.Replace(secret.Redact(value)) + `"`
+}
+
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// histogram counts observations in cumulative buckets, like Prometheus histograms
+type histogram struct {
+	counts []int64
+	count  int64
+	sum    float64
+}
+
+func newHistogram() histogram {
+	return histogram{counts: make([]int64, len(latencyBuckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, bound := range latencyBuckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// write renders the histogram, labels are added to every sample
+func (h *histogram) write(buf *bytes.Buffer, name string, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(buf, "%s_bucket{%s%sle=\"%v\"} %d\n", name, labels, sep, bound, h.counts[i])
+	}
+	fmt.Fprintf(buf, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(buf, "%s_sum%s %v\n", name, labels, h.sum)
+	fmt.Fprintf(buf, "%s_count%s %d\n", name, labels, h.count)
+}
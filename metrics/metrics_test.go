@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/secret"
+)
+
+func TestRender(t *testing.T) {
+	expiry := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	ObserveCheck(request.ResponseLog{URL: "https://google.com", StatusCode: "200", Success: true, LoadTime: 300 * time.Millisecond, TTFB: 80 * time.Millisecond, CertExpiry: expiry})
+	ObserveCheck(request.ResponseLog{URL: "https://google.com", StatusCode: "503"})
+	ObserveCheck(request.ResponseLog{URL: "https://reddit.com", StatusCode: "200", Success: true, Maintenance: true, LoadTime: 2 * time.Second})
+	ObserveDBWrite(20*time.Millisecond, nil)
+	ObserveDBWrite(time.Second, errors.New("write failed"))
+	SetQueueDepth(func() int { return 3 })
+	Retain([]string{"https://google.com"})
+
+	output := string(render())
+	expected := []string{
+		`website_up{url="https://google.com"} 0`,
+		`website_last_status_code{url="https://google.com"} 503`,
+		`website_checks_total{url="https://google.com",outcome="success"} 1`,
+		`website_checks_total{url="https://google.com",outcome="failure"} 1`,
+		`website_response_time_seconds_bucket{url="https://google.com",le="0.25"} 0`,
+		`website_response_time_seconds_bucket{url="https://google.com",le="0.5"} 1`,
+		`website_response_time_seconds_count{url="https://google.com"} 1`,
+		`website_ttfb_seconds_bucket{url="https://google.com",le="0.1"} 1`,
+		`website_certificate_expiry_timestamp_seconds{url="https://google.com"} 1893456000`,
+		`monitor_check_queue_depth 3`,
+		`monitor_db_write_duration_seconds_bucket{le="0.05"} 1`,
+		`monitor_db_write_duration_seconds_count 2`,
+		`monitor_db_write_errors_total 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("missing %q in:\n%v", line, output)
+		}
+	}
+	if strings.Contains(output, "reddit") {
+		t.Errorf("metrics of https://reddit.com should have been dropped:\n%v", output)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"https://google.com", `url="https://google.com"`},
+		{"https://bücher.example/?q=\"a\\b\"\n", `url="https://bücher.example/?q=\"a\\b\"\n"`},
+		{"https://example.com/?token=metrics-test-token", `url="https://example.com/?token=****"`},
+	}
+	secret.Register("metrics-test-token")
+	for _, test := range tests {
+		if got := label("url", test.value); got != test.expected {
+			t.Errorf("label(%q) = %v, want %v", test.value, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Config tells where the metrics are served, on their own listener so they can be scraped without exposing the management API
+// the metrics are disabled when Listen is empty
+type Config struct {
+	Listen string `json:"listen"`
+}
+
+// Enabled tells if the metrics should be served
+func (c Config) Enabled() bool {
+	return c.Listen != ""
+}
+
+// Run serves the metrics on /metrics until the context is done
+func Run(ctx context.Context, config Config) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	server := &http.Server{Addr: config.Listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("metrics server error: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/ayoubed/datadog-home-project/request"
+	"golang.org/x/sync/errgroup"
+)
+
+// Manager starts, stops and reconfigures the monitor of each website
+// every website has its own goroutine, so changing one doesn't interrupt the others
+type Manager struct {
+	ctx  context.Context
+	g    *errgroup.Group
+	logc chan request.ResponseLog
+
+	mu    sync.Mutex
+	sites map[string]*monitoredSite
+	order []string
+}
+
+type monitoredSite struct {
+	website Website
+	// cancel stops the monitor, it is nil for paused websites
+	cancel context.CancelFunc
+}
+
+// NewManager creates a manager whose monitors run in the given errgroup and send their logs to logc
+func NewManager(ctx context.Context, g *errgroup.Group, logc chan request.ResponseLog) *Manager {
+	return &Manager{ctx: ctx, g: g, logc: logc, sites: make(map[string]*monitoredSite)}
+}
+
+// Apply makes the monitored websites match the given list
+// new websites are started, removed and paused ones are stopped and the changed ones are restarted,
+// their monitor keeps its own copy of the website
+func (m *Manager) Apply(websites []Website) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	wanted := make(map[string]bool)
+	order := make([]string, 0, len(websites))
+	for _, ws := range websites {
+		wanted[ws.URL] = true
+		order = append(order, ws.URL)
+
+		current, ok := m.sites[ws.URL]
+		if ok && current.cancel != nil && !reflect.DeepEqual(current.website, ws) {
+			current.cancel()
+			current.cancel = nil
+		}
+		if !ok {
+			current = &monitoredSite{}
+			m.sites[ws.URL] = current
+		}
+		current.website = ws
+		if current.cancel == nil && !ws.Paused {
+			current.cancel = m.start(ws)
+		}
+	}
+
+	for url, site := range m.sites {
+		if !wanted[url] {
+			if site.cancel != nil {
+				site.cancel()
+			}
+			delete(m.sites, url)
+		}
+	}
+	m.order = order
+}
+
+// Websites returns the websites, paused ones included, in configuration order
+func (m *Manager) Websites() []Website {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	websites := make([]Website, 0, len(m.order))
+	for _, url := range m.order {
+		websites = append(websites, m.sites[url].website)
+	}
+	return websites
+}
+
+// Monitored returns the websites that are not paused, in configuration order
+func (m *Manager) Monitored() []Website {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	websites := make([]Website, 0, len(m.order))
+	for _, url := range m.order {
+		if !m.sites[url].website.Paused {
+			websites = append(websites, m.sites[url].website)
+		}
+	}
+	return websites
+}
+
+// URLs returns the URLs of the monitored websites, paused ones excluded, in configuration order
+func (m *Manager) URLs() []string {
+	websites := m.Monitored()
+	urls := make([]string, 0, len(websites))
+	for _, ws := range websites {
+		urls = append(urls, ws.URL)
+	}
+	return urls
+}
+
+// start launches the monitor of a website and returns the function stopping it
+func (m *Manager) start(website Website) context.CancelFunc {
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.g.Go(func() error {
+		return StartWebsiteMonitor(ctx, website, m.logc)
+	})
+	return cancel
+}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/request"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestApplyChangedWebsite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, gctx := errgroup.WithContext(ctx)
+	logc := make(chan request.ResponseLog)
+	m := NewManager(gctx, g, logc)
+
+	next := func() request.ResponseLog {
+		select {
+		case log := <-logc:
+			return log
+		case <-time.After(5 * time.Second):
+			t.Fatal("Got no check")
+		}
+		return request.ResponseLog{}
+	}
+
+	m.Apply([]Website{{URL: server.URL, CheckInterval: 1}})
+	if log := next(); log.Success {
+		t.Fatalf("Got %+v, want a failed check: the website answers 204", log)
+	}
+
+	m.Apply([]Website{{URL: server.URL, CheckInterval: 1, ExpectedStatus: http.StatusNoContent, Tags: map[string]string{"env": "prod"}}})
+	// a check of the previous monitor may still be on its way
+	for i := 0; i < 3; i++ {
+		if next().Success {
+			if ws := m.Websites(); len(ws) != 1 || ws[0].Tags["env"] != "prod" {
+				t.Errorf("Got %+v, want the new tags", ws)
+			}
+			return
+		}
+	}
+	t.Error("The monitor didn't pick up the new expected status")
+}
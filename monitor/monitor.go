@@ -2,21 +2,50 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/ayoubed/datadog-home-project/database"
+	"github.com/ayoubed/datadog-home-project/eventlog"
+	"github.com/ayoubed/datadog-home-project/maintenance"
+	"github.com/ayoubed/datadog-home-project/metrics"
 	"github.com/ayoubed/datadog-home-project/request"
 )
 
+// ErrUnknownWebsite is returned when acting on a website that is not monitored
+var ErrUnknownWebsite = errors.New("unknown website")
+
 // Website representes the entities we want to monitor
+// DependsOn lists the URLs of the monitored websites this one relies on (load balancer, auth service...),
+// its alerts are suppressed while one of them is down
+// Paused websites are not checked
+// Tags (team, env, region...) and Group are used to filter and aggregate the websites in the dashboard and to route their alerts
+// ExpectedStatus is the status code of a successful check, 200 when it's not set
 type Website struct {
-	URL           string `json:"url"`
-	CheckInterval int    `json:"checkInterval"`
+	URL            string            `json:"url"`
+	CheckInterval  int               `json:"checkInterval"`
+	ExpectedStatus int               `json:"expectedStatus,omitempty"`
+	DependsOn      []string          `json:"dependsOn,omitempty"`
+	Paused         bool              `json:"paused,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	Group          string            `json:"group,omitempty"`
+}
+
+// HasTags tells if the website has all the given tags, with the same values
+func (ws Website) HasTags(tags map[string]string) bool {
+	for key, value := range tags {
+		if ws.Tags[key] != value {
+			return false
+		}
+	}
+	return true
 }
 
 // StartWebsiteMonitor starts a ticker for the given website
 // it sends a request following a user-defined interval
+// a request that can't be sent (unknown host, refused connection...) is a failed check whose status code is the error, like in CI mode,
+// it is written to the event log as an error and doesn't stop the monitor
 func StartWebsiteMonitor(ctx context.Context, website Website, logc chan request.ResponseLog) error {
 	ticker := time.NewTicker(time.Duration(website.CheckInterval) * time.Second)
 	for {
@@ -25,25 +54,39 @@ func StartWebsiteMonitor(ctx context.Context, website Website, logc chan request
 			ticker.Stop()
 			return nil
 		case t := <-ticker.C:
-			log, err := request.Send(t, website.URL)
+			log, err := request.Send(t, website.URL, website.ExpectedStatus)
 			if err != nil {
-				return fmt.Errorf("error while monitoring %v:\n Details: %v", website, err)
+				eventlog.Error(fmt.Errorf("error while monitoring %v: %v", website.URL, err))
+				log = request.ResponseLog{Timestamp: t, URL: website.URL, StatusCode: err.Error()}
+			}
+			log.Maintenance = maintenance.Active(website.URL, t)
+			select {
+			case logc <- log:
+			case <-ctx.Done():
+				return nil
 			}
-			logc <- log
 		}
 	}
 }
 
 // ProcessLogs reads logs from the log channel and processes them
 // in our case we write logs in our database
+// a failed write is counted and written to the event log as an error, the next logs are still written
 func ProcessLogs(ctx context.Context, logc chan request.ResponseLog) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return nil
 		case log := <-logc:
-			if err := database.WriteLogToDB(log); err != nil {
-				return fmt.Errorf("error while processing a log:\n %v", err)
+			metrics.ObserveCheck(log)
+			if err := eventlog.Check(log); err != nil {
+				return err
+			}
+			start := time.Now()
+			err := database.WriteLogToDB(log)
+			metrics.ObserveDBWrite(time.Since(start), err)
+			if err != nil {
+				eventlog.Error(fmt.Errorf("error while writing the check of %v: %v", log.URL, err))
 			}
 		}
 	}
@@ -0,0 +1,52 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/request"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestUnreachableWebsite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, gctx := errgroup.WithContext(ctx)
+	logc := make(chan request.ResponseLog)
+	m := NewManager(gctx, g, logc)
+	m.Apply([]Website{
+		{URL: "http://unresolvable.invalid", CheckInterval: 1},
+		{URL: server.URL, CheckInterval: 1},
+	})
+
+	checks := make(map[string][]request.ResponseLog)
+	timeout := time.After(10 * time.Second)
+	for len(checks["http://unresolvable.invalid"]) < 2 || len(checks[server.URL]) < 2 {
+		select {
+		case log := <-logc:
+			checks[log.URL] = append(checks[log.URL], log)
+		case <-gctx.Done():
+			t.Fatalf("The monitors stopped: %v", g.Wait())
+		case <-timeout:
+			t.Fatalf("Got %v, want two checks of each website", checks)
+		}
+	}
+
+	for _, log := range checks["http://unresolvable.invalid"] {
+		if log.Success || !strings.Contains(log.StatusCode, "unresolvable.invalid") {
+			t.Errorf("Got %+v, want a failed check with the error as status code", log)
+		}
+	}
+	for _, log := range checks[server.URL] {
+		if !log.Success {
+			t.Errorf("Got %+v, want a successful check", log)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package request
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"net/http/httptrace"
@@ -17,26 +18,66 @@ type ResponseLog struct {
 	TTFB       time.Duration
 	LoadTime   time.Duration
 	Success    bool
+	// Maintenance is set for checks done during a silence or a maintenance window
+	Maintenance bool
+	// CertExpiry is the expiry date of the TLS certificate of the website, zero for plain HTTP
+	CertExpiry time.Time
+	// DNS, Connect and TLS are the durations of the phases of the request before it was sent
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
 }
 
-// Send performs a request to the given URL
-func Send(t time.Time, url string) (ResponseLog, error) {
+// Timings is the breakdown of the duration of a request
+// DNS, Connect and TLS are zero when the connection is reused or the step doesn't apply
+type Timings struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	Total   time.Duration
+}
+
+// Send performs a request to the given URL, the check succeeds if the response has the expected status code
+// an expectedStatus of 0 expects 200
+func Send(t time.Time, url string, expectedStatus int) (ResponseLog, error) {
+	log, timings, err := inspect(t, url, expectedStatus)
+	log.DNS, log.Connect, log.TLS = timings.DNS, timings.Connect, timings.TLS
+	return log, err
+}
+
+// Inspect performs a request to the given URL and also returns the timing breakdown of the request
+func Inspect(t time.Time, url string, expectedStatus int) (ResponseLog, Timings, error) {
+	return inspect(t, url, expectedStatus)
+}
+
+func inspect(t time.Time, url string, expectedStatus int) (ResponseLog, Timings, error) {
+	if expectedStatus == 0 {
+		expectedStatus = http.StatusOK
+	}
 	var (
-		start time.Time
-		ttfb  time.Duration
+		start                            time.Time
+		timings                          Timings
+		dnsStart, connectStart, tlsStart time.Time
 	)
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return ResponseLog{}, err
+		return ResponseLog{}, Timings{}, err
 	}
 	client := &http.Client{
 		Timeout: 15 * time.Second,
 	}
 
 	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { timings.DNS = time.Since(dnsStart) },
+		ConnectStart:      func(string, string) { connectStart = time.Now() },
+		ConnectDone:       func(string, string, error) { timings.Connect = time.Since(connectStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { timings.TLS = time.Since(tlsStart) },
 		GotFirstResponseByte: func() {
-			ttfb = time.Since(start)
+			timings.TTFB = time.Since(start)
 		},
 	}
 	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
@@ -44,15 +85,21 @@ func Send(t time.Time, url string) (ResponseLog, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
+		timings.Total = time.Since(start)
 		if err, ok := err.(net.Error); ok && err.Timeout() {
-			return ResponseLog{t, err.Error(), url, time.Duration(0), time.Duration(0), false}, nil
+			return ResponseLog{Timestamp: t, StatusCode: err.Error(), URL: url}, timings, nil
 		}
-		return ResponseLog{}, err
+		return ResponseLog{}, timings, err
 	}
 	defer resp.Body.Close()
+	timings.Total = time.Since(start)
 
-	if resp.StatusCode != http.StatusOK {
-		return ResponseLog{t, strconv.Itoa(resp.StatusCode), url, time.Duration(0), time.Duration(0), false}, nil
+	if resp.StatusCode != expectedStatus {
+		return ResponseLog{Timestamp: t, StatusCode: strconv.Itoa(resp.StatusCode), URL: url}, timings, nil
+	}
+	log := ResponseLog{Timestamp: t, StatusCode: strconv.Itoa(resp.StatusCode), URL: url, TTFB: timings.TTFB, LoadTime: timings.Total, Success: true}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		log.CertExpiry = resp.TLS.PeerCertificates[0].NotAfter
 	}
-	return ResponseLog{t, strconv.Itoa(resp.StatusCode), url, ttfb, time.Since(start), true}, nil
+	return log, timings, nil
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/api"
+	"github.com/ayoubed/datadog-home-project/dashboard"
+	"github.com/ayoubed/datadog-home-project/eventlog"
+	"github.com/ayoubed/datadog-home-project/maintenance"
+	"github.com/ayoubed/datadog-home-project/metrics"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/request"
+	"github.com/ayoubed/datadog-home-project/statuspage"
+	"github.com/ayoubed/datadog-home-project/web"
+	"golang.org/x/sync/errgroup"
+)
+
+// logQueueSize is the number of checks that can wait to be written to the database
+const logQueueSize = 100
+
+// runCommand monitors the websites of the config, with the dashboard unless it runs headless
+func runCommand(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	configFile := flags.String("config", defaultConfig, "config file, in JSON, YAML or TOML")
+	headless := flags.Bool("headless", false, "run without the dashboard, writing events as JSON lines")
+	eventsFile := flags.String("events", "", "file the JSON-lines events are appended to, - for the standard output (default - in headless mode)")
+	plain := flags.Bool("plain", false, "print the views and the alerts as lines instead of drawing the dashboard (default when the output is not a terminal)")
+	format := flags.String("format", dashboard.FormatText, "format of the plain output, text or csv")
+	flags.Parse(args)
+
+	if !*headless && !isTerminal(os.Stdout) {
+		*plain = true
+	}
+
+	if *headless && *eventsFile == "" {
+		*eventsFile = "-"
+	}
+	if *eventsFile != "" {
+		events, err := openEvents(*eventsFile)
+		if err != nil {
+			return err
+		}
+		defer events.Close()
+		eventlog.SetOutput(events)
+	}
+
+	cfg, err := loadWithDatabase(*configFile)
+	if err != nil {
+		return err
+	}
+
+	if err := maintenance.Set(cfg.Maintenance); err != nil {
+		return fmt.Errorf("error setting up the maintenance windows: %v", err)
+	}
+
+	ctx, done := context.WithCancel(context.Background())
+	g, gctx := errgroup.WithContext(ctx)
+
+	logc := make(chan request.ResponseLog, logQueueSize)
+	metrics.SetQueueDepth(func() int { return len(logc) })
+	alertc := make(chan string)
+	viewc := make(chan []dashboard.View)
+	alertReloadc := make(chan alerting.Reload)
+	// alertc is not closed: the notifications and the dashboard send to it from goroutines that may outlive the errgroup
+	defer close(logc)
+
+	manager := monitor.NewManager(gctx, g, logc)
+	manager.Apply(cfg.Websites)
+	c := &controller{ctx: gctx, path: *configFile, current: cfg, manager: manager, viewc: viewc, alertReloadc: alertReloadc, alertc: alertc}
+
+	if *headless {
+		g.Go(func() error {
+			return drainDashboard(gctx, alertc, viewc)
+		})
+		g.Go(func() error {
+			return waitForSignal(gctx, done)
+		})
+	} else if *plain {
+		g.Go(func() error {
+			return dashboard.RunPlain(gctx, os.Stdout, *format, manager.Monitored, cfg.Dashboard, viewc, alertc)
+		})
+		g.Go(func() error {
+			return waitForSignal(gctx, done)
+		})
+	} else {
+		g.Go(func() error {
+			return dashboard.Run(gctx, manager.Monitored, c.Add, cfg.Dashboard, viewc, alertc, done)
+		})
+	}
+	if *eventsFile != "" {
+		alerts, unsubscribe := alerting.Subscribe(alertEventsBuffer)
+		defer unsubscribe()
+		g.Go(func() error {
+			return logAlerts(gctx, alerts)
+		})
+	}
+	g.Go(func() error {
+		return alerting.Run(gctx, alertc, cfg.Websites, cfg.Alert, alertReloadc)
+	})
+
+	g.Go(func() error {
+		return monitor.ProcessLogs(gctx, logc)
+	})
+
+	g.Go(func() error {
+		return c.run()
+	})
+
+	if cfg.API.Enabled() {
+		g.Go(func() error {
+			return api.Run(gctx, cfg.API, c)
+		})
+	}
+
+	if cfg.Web.Enabled() {
+		g.Go(func() error {
+			return web.Run(gctx, cfg.Web, manager.Monitored, c.Views)
+		})
+	}
+
+	if cfg.Metrics.Enabled() {
+		g.Go(func() error {
+			return metrics.Run(gctx, cfg.Metrics)
+		})
+	}
+
+	if cfg.StatusPage.Enabled() {
+		g.Go(func() error {
+			return statuspage.Run(gctx, cfg.StatusPage, manager.Monitored)
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		eventlog.Error(err)
+		return err
+	}
+	return nil
+}
+
+// isTerminal tells if a file is a terminal, rather than a pipe or a regular file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,42 @@
+package secret
+
+import (
+	"strings"
+	"sync"
+)
+
+// Mask replaces the secrets in redacted text
+const Mask = "****"
+
+// minLength is the length under which values are not registered, masking them would garble unrelated text
+const minLength = 4
+
+var (
+	secrets []string
+	mu      sync.RWMutex
+)
+
+// Register adds a value to hide from the logs, the API and the dashboard
+func Register(value string) {
+	if len(value) < minLength {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range secrets {
+		if s == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+}
+
+// Redact replaces the registered secrets found in s with Mask
+func Redact(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Mask)
+	}
+	return s
+}
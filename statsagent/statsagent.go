@@ -2,6 +2,8 @@ package statsagent
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/ayoubed/datadog-home-project/database"
@@ -9,6 +11,8 @@ import (
 )
 
 // WebsiteStats contains useful metrics about website
+// Successes is the number of successful checks, the ones the average times are computed on,
+// and Counted the number of checks outside maintenance, the ones the availability is computed on
 type WebsiteStats struct {
 	StatusCodeCount    map[string]int
 	AvgResponseTime    time.Duration
@@ -16,6 +20,8 @@ type WebsiteStats struct {
 	AvgTimeToFirstByte time.Duration
 	MaxTimeToFirstByte time.Duration
 	Availability       float64
+	Successes          int
+	Counted            int
 }
 
 // GetStats of provided websites for a particular timeframe
@@ -36,8 +42,18 @@ func GetStats(urls []string, origin time.Time, timeframe int64) (map[string]Webs
 		var avgTimeToFirstByte float64 = 0
 		var successCount float64 = 0
 		var availability float64 = 0
+		// checks done during maintenance are excluded from the availability
+		var countedRecords float64 = 0
+		var countedSuccess float64 = 0
 
 		for _, line := range records {
+			if !line.Maintenance {
+				countedRecords++
+				if line.Success {
+					countedSuccess++
+				}
+			}
+
 			if _, ok := statusCodeCount[line.StatusCode]; ok {
 				statusCodeCount[line.StatusCode]++
 			} else {
@@ -59,13 +75,49 @@ func GetStats(urls []string, origin time.Time, timeframe int64) (map[string]Webs
 		if successCount > 0 {
 			avgResponseTime = float64(sumResponseTime) / float64(successCount)
 			avgTimeToFirstByte = float64(sumTimeToFirstByte) / float64(successCount)
-			availability = successCount / float64(len(records))
 		}
-		websitesStats[url] = WebsiteStats{StatusCodeCount: statusCodeCount, AvgResponseTime: time.Duration(avgResponseTime), MaxResponseTime: maxResponseTime, AvgTimeToFirstByte: time.Duration(avgTimeToFirstByte), MaxTimeToFirstByte: maxTimeToFirstByte, Availability: availability}
+		if countedRecords > 0 {
+			availability = countedSuccess / countedRecords
+		}
+		websitesStats[url] = WebsiteStats{StatusCodeCount: statusCodeCount, AvgResponseTime: time.Duration(avgResponseTime), MaxResponseTime: maxResponseTime, AvgTimeToFirstByte: time.Duration(avgTimeToFirstByte), MaxTimeToFirstByte: maxTimeToFirstByte, Availability: availability,
+			Successes: int(successCount), Counted: int(countedRecords)}
 	}
 	return websitesStats, nil
 }
 
+// AggregateStats combines the stats of a group of websites
+// the availability is weighted by the number of checks outside maintenance of each website, the average times by the number of successful checks
+// and the maximums are the largest ones
+func AggregateStats(stats []WebsiteStats) WebsiteStats {
+	res := WebsiteStats{StatusCodeCount: make(map[string]int)}
+	var responseTime, timeToFirstByte, availability float64
+	for _, s := range stats {
+		for code, n := range s.StatusCodeCount {
+			res.StatusCodeCount[code] += n
+		}
+		res.Successes += s.Successes
+		res.Counted += s.Counted
+		availability += s.Availability * float64(s.Counted)
+		responseTime += float64(s.AvgResponseTime) * float64(s.Successes)
+		timeToFirstByte += float64(s.AvgTimeToFirstByte) * float64(s.Successes)
+
+		if s.MaxResponseTime > res.MaxResponseTime {
+			res.MaxResponseTime = s.MaxResponseTime
+		}
+		if s.MaxTimeToFirstByte > res.MaxTimeToFirstByte {
+			res.MaxTimeToFirstByte = s.MaxTimeToFirstByte
+		}
+	}
+	if res.Counted > 0 {
+		res.Availability = availability / float64(res.Counted)
+	}
+	if res.Successes > 0 {
+		res.AvgResponseTime = time.Duration(responseTime / float64(res.Successes))
+		res.AvgTimeToFirstByte = time.Duration(timeToFirstByte / float64(res.Successes))
+	}
+	return res
+}
+
 type AvailabilityRange struct {
 	Availability float64
 	Start        time.Time
@@ -82,20 +134,192 @@ func GetAvailabilityForTimeFrame(url string, origin time.Time, timeframe int64)
 }
 
 // GetAvailabilityForRecords returns the availability given a slice of records
+// records of checks done during maintenance are ignored
 func GetAvailabilityForRecords(records []request.ResponseLog, origin time.Time) AvailabilityRange {
 	var start time.Time = origin
 	var successCount float64 = 0
 	var availability float64 = 0
 
+	counted := make([]request.ResponseLog, 0, len(records))
 	for _, line := range records {
+		if line.Maintenance {
+			continue
+		}
+		counted = append(counted, line)
 		if line.Success {
 			successCount++
 		}
 	}
 
 	if successCount > 0 {
-		availability = successCount / float64(len(records))
-		start = records[0].Timestamp
+		availability = successCount / float64(len(counted))
+		start = counted[0].Timestamp
 	}
 	return AvailabilityRange{Availability: availability, Start: start}
 }
+
+// SeriesPoint aggregates the checks of a website over a slice of a timeframe
+// Successes is the number of successful checks, the ones the average response time is computed on,
+// and Counted the number of checks outside maintenance, the ones the availability is computed on
+type SeriesPoint struct {
+	Start           time.Time
+	AvgResponseTime time.Duration
+	Availability    float64
+	Count           int
+	Successes       int
+	Counted         int
+}
+
+// GetSeries splits the timeframe ending at origin in buckets of equal length,
+// and aggregates the records of a website in each of them
+func GetSeries(url string, origin time.Time, timeframe int64, buckets int) ([]SeriesPoint, error) {
+	records, err := database.GetRecordsForURL(url, origin, timeframe)
+	if err != nil {
+		return nil, fmt.Errorf("error while computing the series of %v: %v", url, err)
+	}
+	return GetSeriesForRecords(records, origin, timeframe, buckets), nil
+}
+
+// GetSeriesForRecords aggregates records in buckets, buckets without records have a zero Count
+// records of checks done during maintenance count for the response time but not for the availability
+// the series is empty when there are no buckets
+func GetSeriesForRecords(records []request.ResponseLog, origin time.Time, timeframe int64, buckets int) []SeriesPoint {
+	if buckets <= 0 {
+		return []SeriesPoint{}
+	}
+	start := origin.Add(-time.Duration(timeframe) * time.Second)
+	width := time.Duration(timeframe) * time.Second / time.Duration(buckets)
+
+	points := make([]SeriesPoint, buckets)
+	sumResponseTime := make([]time.Duration, buckets)
+	successCount := make([]int, buckets)
+	countedRecords := make([]int, buckets)
+	countedSuccess := make([]int, buckets)
+	for i := range points {
+		points[i].Start = start.Add(time.Duration(i) * width)
+	}
+
+	for _, line := range records {
+		i := int(line.Timestamp.Sub(start) / width)
+		if i < 0 || width <= 0 {
+			continue
+		}
+		if i >= buckets {
+			i = buckets - 1
+		}
+		points[i].Count++
+		if line.Success {
+			successCount[i]++
+			sumResponseTime[i] += line.LoadTime
+		}
+		if !line.Maintenance {
+			countedRecords[i]++
+			if line.Success {
+				countedSuccess[i]++
+			}
+		}
+	}
+
+	for i := range points {
+		points[i].Successes = successCount[i]
+		points[i].Counted = countedRecords[i]
+		if successCount[i] > 0 {
+			points[i].AvgResponseTime = sumResponseTime[i] / time.Duration(successCount[i])
+		}
+		if countedRecords[i] > 0 {
+			points[i].Availability = float64(countedSuccess[i]) / float64(countedRecords[i])
+		}
+	}
+	return points
+}
+
+// AggregateSeries combines the series of a group of websites, computed over the same timeframe and number of buckets
+// like AggregateStats, the availability is weighted by the number of checks outside maintenance and the response time by the number of successful checks
+func AggregateSeries(series [][]SeriesPoint) []SeriesPoint {
+	if len(series) == 0 {
+		return nil
+	}
+	res := make([]SeriesPoint, len(series[0]))
+	for i := range res {
+		var availability, responseTime float64
+		for _, points := range series {
+			if i >= len(points) {
+				continue
+			}
+			p := points[i]
+			res[i].Start = p.Start
+			res[i].Count += p.Count
+			res[i].Successes += p.Successes
+			res[i].Counted += p.Counted
+			availability += p.Availability * float64(p.Counted)
+			responseTime += float64(p.AvgResponseTime) * float64(p.Successes)
+		}
+		if res[i].Counted > 0 {
+			res[i].Availability = availability / float64(res[i].Counted)
+		}
+		if res[i].Successes > 0 {
+			res[i].AvgResponseTime = time.Duration(responseTime / float64(res[i].Successes))
+		}
+	}
+	return res
+}
+
+// DayAvailability is the availability of a website during a day, Checks is zero for days without data
+type DayAvailability struct {
+	Day          time.Time
+	Availability float64
+	Checks       int64
+}
+
+// GetDailyAvailability computes the availability of a website for each of the last days, oldest first
+// it also returns the availability over the whole period
+func GetDailyAvailability(url string, origin time.Time, days int) ([]DayAvailability, float64, error) {
+	from := origin.UTC().Truncate(24*time.Hour).AddDate(0, 0, -(days - 1))
+	counts, err := database.GetDailyCounts(url, from, origin)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byDay := make(map[time.Time]database.DailyCount)
+	for _, count := range counts {
+		byDay[count.Day.UTC()] = count
+	}
+
+	res := make([]DayAvailability, 0, days)
+	var counted, success int64
+	for i := 0; i < days; i++ {
+		day := from.AddDate(0, 0, i)
+		count := byDay[day]
+		item := DayAvailability{Day: day, Checks: count.Total - count.Maintenance}
+		if item.Checks > 0 {
+			item.Availability = float64(count.Success-count.SuccessMaintenance) / float64(item.Checks)
+		}
+		counted += item.Checks
+		success += count.Success - count.SuccessMaintenance
+		res = append(res, item)
+	}
+
+	var availability float64
+	if counted > 0 {
+		availability = float64(success) / float64(counted)
+	}
+	return res, availability, nil
+}
+
+// Milliseconds converts a duration to a number of milliseconds, the unit of the durations of the reports
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// FormatStatusCodes lists the status codes and their count ordered by status code, like 200:12 500:1
+func FormatStatusCodes(counts map[string]int) string {
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for i, code := range codes {
+		codes[i] = fmt.Sprintf("%v:%d", code, counts[code])
+	}
+	return strings.Join(codes, " ")
+}
@@ -0,0 +1,80 @@
+package statsagent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/request"
+)
+
+func TestAggregateStats(t *testing.T) {
+	stats := []WebsiteStats{
+		{StatusCodeCount: map[string]int{"200": 3, "500": 1}, Successes: 3, Counted: 4, Availability: 0.75, AvgResponseTime: 100 * time.Millisecond, MaxResponseTime: 200 * time.Millisecond},
+		// a website expecting a redirection
+		{StatusCodeCount: map[string]int{"301": 1}, Successes: 1, Counted: 1, Availability: 1, AvgResponseTime: 300 * time.Millisecond, MaxResponseTime: 300 * time.Millisecond},
+		// a website in maintenance, its checks don't count for the availability
+		{StatusCodeCount: map[string]int{"503": 5}},
+		{StatusCodeCount: map[string]int{}},
+	}
+
+	res := AggregateStats(stats)
+	if res.Availability != 0.8 || res.AvgResponseTime != 150*time.Millisecond || res.MaxResponseTime != 300*time.Millisecond {
+		t.Errorf("Got %+v, want an availability of 0.8, an average of 150ms and a maximum of 300ms", res)
+	}
+	if res.StatusCodeCount["200"] != 3 || res.StatusCodeCount["301"] != 1 || res.StatusCodeCount["503"] != 5 {
+		t.Errorf("Got %v, want the status codes of every website", res.StatusCodeCount)
+	}
+	if res.Successes != 4 || res.Counted != 5 {
+		t.Errorf("Got %d successes and %d counted checks, want 4 and 5", res.Successes, res.Counted)
+	}
+}
+
+func TestAggregateSeries(t *testing.T) {
+	series := [][]SeriesPoint{
+		{{Count: 6, Successes: 3, Counted: 4, Availability: 0.75, AvgResponseTime: 100 * time.Millisecond}, {}},
+		{{Count: 1, Successes: 1, Counted: 1, Availability: 1, AvgResponseTime: 300 * time.Millisecond}, {Count: 2, Successes: 2, Counted: 2, Availability: 1, AvgResponseTime: 50 * time.Millisecond}},
+	}
+
+	res := AggregateSeries(series)
+	if len(res) != 2 {
+		t.Fatalf("Got %d points, want 2", len(res))
+	}
+	if res[0].Count != 7 || res[0].Availability != 0.8 || res[0].AvgResponseTime != 150*time.Millisecond {
+		t.Errorf("Got %+v, want 7 checks, an availability of 0.8 and an average of 150ms", res[0])
+	}
+	if res[1].Count != 2 || res[1].AvgResponseTime != 50*time.Millisecond {
+		t.Errorf("Got %+v, want the point of the only website with checks", res[1])
+	}
+}
+
+func TestMaintenanceIsExcluded(t *testing.T) {
+	origin := time.Date(2020, 5, 10, 18, 0, 0, 0, time.UTC)
+	records := []request.ResponseLog{
+		{Timestamp: origin.Add(-50 * time.Second), Success: true, LoadTime: 100 * time.Millisecond},
+		{Timestamp: origin.Add(-40 * time.Second), Maintenance: true},
+		{Timestamp: origin.Add(-30 * time.Second), Maintenance: true},
+		{Timestamp: origin.Add(-20 * time.Second), Success: true, Maintenance: true, LoadTime: 300 * time.Millisecond},
+		{Timestamp: origin.Add(-10 * time.Second)},
+	}
+
+	if res := GetAvailabilityForRecords(records, origin); res.Availability != 0.5 {
+		t.Errorf("Got an availability of %v, want 0.5: the checks during maintenance don't count", res.Availability)
+	}
+
+	series := GetSeriesForRecords(records, origin, 60, 1)
+	if len(series) != 1 || series[0].Availability != 0.5 || series[0].Count != 5 || series[0].Counted != 2 || series[0].AvgResponseTime != 200*time.Millisecond {
+		t.Errorf("Got %+v, want an availability of 0.5 over 2 of the 5 checks, and an average over both successful checks", series)
+	}
+	if series := GetSeriesForRecords(records, origin, 60, 0); len(series) != 0 {
+		t.Errorf("Got %+v, want an empty series without buckets", series)
+	}
+}
+
+func TestFormatStatusCodes(t *testing.T) {
+	if res := FormatStatusCodes(map[string]int{"503": 1, "200": 12, "404": 2}); res != "200:12 404:2 503:1" {
+		t.Errorf("Got %q, want the codes ordered", res)
+	}
+	if res := FormatStatusCodes(nil); res != "" {
+		t.Errorf("Got %q, want an empty string", res)
+	}
+}
@@ -0,0 +1,292 @@
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/eventlog"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+)
+
+// Config of the status page, it is generated in Directory every Interval seconds (default 300),
+// with uptime bars for the last Days days (default 90)
+// the status page is disabled when Directory is empty
+type Config struct {
+	Directory string `json:"directory"`
+	Interval  int    `json:"interval"`
+	Title     string `json:"title"`
+	Days      int    `json:"days"`
+}
+
+// Enabled tells if the status page should be generated
+func (c Config) Enabled() bool {
+	return c.Directory != ""
+}
+
+func (c Config) withDefaults() Config {
+	if c.Interval <= 0 {
+		c.Interval = 300
+	}
+	if c.Days <= 0 {
+		c.Days = 90
+	}
+	if c.Title == "" {
+		c.Title = "Status"
+	}
+	return c
+}
+
+// Page is the content of the status page, it is also written as status.json
+// the URLs are redacted, the page being public
+type Page struct {
+	Title     string     `json:"title"`
+	Generated time.Time  `json:"generated"`
+	Groups    []Group    `json:"groups"`
+	Sites     []Site     `json:"sites"`
+	Incidents []Incident `json:"incidents"`
+}
+
+// Site is the status and the uptime history of a website
+type Site struct {
+	URL    string `json:"url"`
+	Status string `json:"status"`
+	// Uptime is the availability over the whole period
+	Uptime float64 `json:"uptime"`
+	Days   []Day   `json:"days"`
+}
+
+// Group is the status and the uptime history of the websites sharing a group,
+// its status is the worst status of its websites and its availability is weighted by their number of checks
+type Group struct {
+	Name   string   `json:"name"`
+	Sites  []string `json:"sites"`
+	Status string   `json:"status"`
+	Uptime float64  `json:"uptime"`
+	Days   []Day    `json:"days"`
+}
+
+// Day is the availability of a website during a day, Checks is zero for days without data
+type Day struct {
+	Day          time.Time `json:"day"`
+	Availability float64   `json:"availability"`
+	Checks       int64     `json:"checks"`
+}
+
+// Incident is a period during which a website was down, End is null for ongoing incidents
+type Incident struct {
+	URL      string     `json:"url"`
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end"`
+	Duration string     `json:"duration"`
+}
+
+// Run generates the status page right away, then every interval until the context is done
+// a failed generation is logged and retried at the next interval, the previous page stays published meanwhile
+func Run(ctx context.Context, config Config, sites func() []monitor.Website) error {
+	config = config.withDefaults()
+	if err := Generate(config, sites(), time.Now()); err != nil {
+		eventlog.Error(err)
+	}
+
+	ticker := time.NewTicker(time.Duration(config.Interval) * time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			return nil
+		case t := <-ticker.C:
+			if err := Generate(config, sites(), t); err != nil {
+				eventlog.Error(err)
+			}
+		}
+	}
+}
+
+// Generate writes index.html and status.json for the given websites in the directory of the config
+func Generate(config Config, sites []monitor.Website, now time.Time) error {
+	config = config.withDefaults()
+	page, err := build(config, sites, now)
+	if err != nil {
+		return fmt.Errorf("error building the status page: %v", err)
+	}
+
+	if err := os.MkdirAll(config.Directory, 0755); err != nil {
+		return fmt.Errorf("error creating the status page directory: %v", err)
+	}
+
+	content, err := json.MarshalIndent(page, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(config.Directory, "status.json"), content); err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("status").Funcs(template.FuncMap{
+		"percent": func(v float64) string { return fmt.Sprintf("%.2f%%", 100*v) },
+		"level":   level,
+		"date":    func(t time.Time) string { return t.Format("Jan 2, 2006") },
+		"time":    func(t time.Time) string { return t.Format(time.RFC1123) },
+	}).Parse(pageTemplate)
+	if err != nil {
+		return err
+	}
+	var html bytes.Buffer
+	if err := tmpl.Execute(&html, page); err != nil {
+		return fmt.Errorf("error rendering the status page: %v", err)
+	}
+	return writeFile(filepath.Join(config.Directory, "index.html"), html.Bytes())
+}
+
+func build(config Config, sites []monitor.Website, now time.Time) (Page, error) {
+	page := Page{Title: config.Title, Generated: now, Groups: make([]Group, 0), Sites: make([]Site, 0, len(sites)), Incidents: make([]Incident, 0)}
+
+	for _, ws := range sites {
+		url := ws.URL
+		days, uptime, err := statsagent.GetDailyAvailability(url, now, config.Days)
+		if err != nil {
+			return Page{}, err
+		}
+		status, ok := alerting.Status(url)
+		if !ok {
+			status = "unknown"
+		}
+
+		site := Site{URL: secret.Redact(url), Status: status, Uptime: uptime, Days: make([]Day, 0, len(days))}
+		for _, day := range days {
+			site.Days = append(site.Days, Day{Day: day.Day, Availability: day.Availability, Checks: day.Checks})
+		}
+		page.Sites = append(page.Sites, site)
+	}
+	page.Groups = groups(sites, page.Sites)
+
+	// only the alerts of the period shown are loaded, along with the start of the incidents ongoing at its beginning
+	since := now.AddDate(0, 0, -config.Days)
+	urls := make([]string, 0, len(sites))
+	for _, ws := range sites {
+		urls = append(urls, ws.URL)
+	}
+	alerts, err := alerting.HistorySince(urls, since)
+	if err != nil {
+		return Page{}, err
+	}
+	page.Incidents = incidents(alerts, urls, since, now)
+	return page, nil
+}
+
+// incidents lists the incidents of the given websites that were ongoing after since, most recent first
+func incidents(alerts []alerting.Alert, urls []string, since time.Time, now time.Time) []Incident {
+	monitored := make(map[string]bool)
+	for _, url := range urls {
+		monitored[url] = true
+	}
+
+	res := make([]Incident, 0)
+	all := alerting.Incidents(alerts)
+	for i := len(all) - 1; i >= 0; i-- {
+		incident := all[i]
+		if !monitored[incident.URL] || (!incident.Ongoing() && incident.End.Before(since)) {
+			continue
+		}
+		item := Incident{URL: secret.Redact(incident.URL), Start: incident.Start, Duration: incident.Duration(now).Round(time.Second).String()}
+		if !incident.Ongoing() {
+			end := incident.End
+			item.End = &end
+		}
+		res = append(res, item)
+	}
+	return res
+}
+
+// groups aggregates the sites by the group of their website, in configuration order, websites without a group are left out
+func groups(websites []monitor.Website, sites []Site) []Group {
+	res := make([]Group, 0)
+	index := make(map[string]int)
+	// the successful checks of each group, to weight the availabilities by the checks of each website
+	successes := make([]float64, 0)
+	for i, ws := range websites {
+		if ws.Group == "" {
+			continue
+		}
+		if _, ok := index[ws.Group]; !ok {
+			index[ws.Group] = len(res)
+			res = append(res, Group{Name: ws.Group, Sites: make([]string, 0), Status: "unknown", Days: make([]Day, len(sites[i].Days))})
+			successes = append(successes, 0)
+		}
+		n := index[ws.Group]
+		group, site := &res[n], sites[i]
+		group.Sites = append(group.Sites, site.URL)
+		if severity(site.Status) > severity(group.Status) {
+			group.Status = site.Status
+		}
+		for d, day := range site.Days {
+			success := group.Days[d].Availability*float64(group.Days[d].Checks) + day.Availability*float64(day.Checks)
+			group.Days[d].Day = day.Day
+			group.Days[d].Checks += day.Checks
+			if group.Days[d].Checks > 0 {
+				group.Days[d].Availability = success / float64(group.Days[d].Checks)
+			}
+			successes[n] += day.Availability * float64(day.Checks)
+		}
+	}
+
+	for n := range res {
+		var checks int64
+		for _, day := range res[n].Days {
+			checks += day.Checks
+		}
+		if checks > 0 {
+			res[n].Uptime = successes[n] / float64(checks)
+		}
+	}
+	return res
+}
+
+// severity orders the website statuses, from the best to the worst
+func severity(status string) int {
+	switch status {
+	case alerting.StatusUp:
+		return 1
+	case alerting.StatusFlapping:
+		return 2
+	case alerting.StatusDown:
+		return 3
+	}
+	return 0
+}
+
+// level is the CSS class of an uptime bar
+func level(day Day) string {
+	switch {
+	case day.Checks == 0:
+		return "nodata"
+	case day.Availability >= 0.99:
+		return "good"
+	case day.Availability >= 0.95:
+		return "degraded"
+	default:
+		return "bad"
+	}
+}
+
+// writeFile replaces a file atomically, so the published page is never half written
+func writeFile(path string, content []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0644); err != nil {
+		return fmt.Errorf("error writing %v: %v", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing %v: %v", path, err)
+	}
+	return nil
+}
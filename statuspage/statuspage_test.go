@@ -0,0 +1,94 @@
+package statuspage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/monitor"
+)
+
+func TestGroups(t *testing.T) {
+	day := time.Date(2020, 5, 10, 0, 0, 0, 0, time.UTC)
+	websites := []monitor.Website{
+		{URL: "https://a.com", Group: "shop"},
+		{URL: "https://b.com"},
+		{URL: "https://c.com", Group: "shop"},
+		{URL: "https://d.com", Group: "blog"},
+	}
+	sites := []Site{
+		{URL: "https://a.com", Status: alerting.StatusUp, Days: []Day{{Day: day, Availability: 1, Checks: 30}, {Day: day.AddDate(0, 0, 1)}}},
+		{URL: "https://b.com", Status: alerting.StatusDown, Days: []Day{{Day: day, Availability: 0, Checks: 10}, {Day: day.AddDate(0, 0, 1)}}},
+		{URL: "https://c.com", Status: alerting.StatusFlapping, Days: []Day{{Day: day, Availability: 0.5, Checks: 10}, {Day: day.AddDate(0, 0, 1), Availability: 1, Checks: 10}}},
+		{URL: "https://d.com", Status: "unknown", Days: []Day{{Day: day}, {Day: day.AddDate(0, 0, 1)}}},
+	}
+
+	res := groups(websites, sites)
+	if len(res) != 2 || res[0].Name != "shop" || res[1].Name != "blog" {
+		t.Fatalf("Got %+v, want the shop and blog groups, in configuration order", res)
+	}
+
+	shop := res[0]
+	if len(shop.Sites) != 2 || shop.Status != alerting.StatusFlapping {
+		t.Errorf("Got %+v, want 2 websites and the worst status", shop)
+	}
+	// 45 successful checks out of 50
+	if shop.Uptime != 0.9 {
+		t.Errorf("Got an uptime of %v, want 0.9, weighted by the checks of each website", shop.Uptime)
+	}
+	if len(shop.Days) != 2 || shop.Days[0].Checks != 40 || shop.Days[0].Availability != 0.875 || shop.Days[1].Checks != 10 || shop.Days[1].Availability != 1 {
+		t.Errorf("Got days %+v, want the checks of both websites added up", shop.Days)
+	}
+
+	blog := res[1]
+	if blog.Status != "unknown" || blog.Uptime != 0 || level(blog.Days[0]) != "nodata" {
+		t.Errorf("Got %+v, want an unknown group without data", blog)
+	}
+}
+
+func TestIncidents(t *testing.T) {
+	now := time.Date(2020, 5, 10, 18, 0, 0, 0, time.UTC)
+	since := now.AddDate(0, 0, -1)
+	down := func(url string, t time.Time) alerting.Alert {
+		return alerting.Alert{URL: url, Time: t, Severity: alerting.SeverityCritical}
+	}
+	up := func(url string, t time.Time) alerting.Alert {
+		return alerting.Alert{URL: url, Up: true, Time: t, Severity: alerting.SeverityInfo}
+	}
+	alerts := []alerting.Alert{
+		down("https://a.com", since.Add(-2*time.Hour)),
+		up("https://a.com", since.Add(-time.Hour)),
+		down("https://b.com", since.Add(-time.Hour)),
+		up("https://b.com", since.Add(time.Hour)),
+		down("https://removed.com", since.Add(2*time.Hour)),
+		down("https://a.com", now.Add(-time.Hour)),
+	}
+
+	res := incidents(alerts, []string{"https://a.com", "https://b.com"}, since, now)
+	if len(res) != 2 {
+		t.Fatalf("Got %+v, want the incidents of https://a.com and https://b.com ongoing during the period", res)
+	}
+	if res[0].URL != "https://a.com" || res[0].End != nil || res[0].Duration != "1h0m0s" {
+		t.Errorf("Got %+v, want the ongoing incident of https://a.com first", res[0])
+	}
+	if res[1].URL != "https://b.com" || res[1].End == nil || !res[1].Start.Equal(since.Add(-time.Hour)) || res[1].Duration != "2h0m0s" {
+		t.Errorf("Got %+v, want the incident of https://b.com that started before the period", res[1])
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		day  Day
+		want string
+	}{
+		{Day{}, "nodata"},
+		{Day{Availability: 1, Checks: 10}, "good"},
+		{Day{Availability: 0.97, Checks: 100}, "degraded"},
+		{Day{Availability: 0.5, Checks: 10}, "bad"},
+	}
+	for _, test := range tests {
+		if res := level(test.day); res != test.want {
+			t.Errorf("level(%+v) = %v, want %v", test.day, res, test.want)
+		}
+	}
+}
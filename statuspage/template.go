@@ -0,0 +1,53 @@
+package statuspage
+
+const pageTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 900px; margin: 2em auto; color: #222; }
+.site { margin-bottom: 1.5em; }
+.site h2 { font-size: 1.1em; display: flex; justify-content: space-between; }
+.status { text-transform: uppercase; font-size: 0.8em; }
+.status.up { color: #2e7d32; }
+.status.down { color: #c62828; }
+.status.flapping, .status.unknown { color: #f9a825; }
+.bars { display: flex; gap: 1px; height: 30px; }
+.bars span { flex: 1; border-radius: 2px; }
+.good { background: #4caf50; }
+.degraded { background: #fbc02d; }
+.bad { background: #e53935; }
+.nodata { background: #ccc; }
+.incidents li { margin-bottom: 0.5em; }
+footer { color: #888; font-size: 0.8em; margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Groups}}
+<div class="site">
+<h2><span>{{.Name}} ({{len .Sites}})</span><span class="status {{.Status}}">{{.Status}} &middot; {{percent .Uptime}}</span></h2>
+<div class="bars">{{range .Days}}<span class="{{level .}}" title="{{date .Day}}: {{if .Checks}}{{percent .Availability}}{{else}}no data{{end}}"></span>{{end}}</div>
+</div>
+{{end}}
+{{range .Sites}}
+<div class="site">
+<h2><span>{{.URL}}</span><span class="status {{.Status}}">{{.Status}} &middot; {{percent .Uptime}}</span></h2>
+<div class="bars">{{range .Days}}<span class="{{level .}}" title="{{date .Day}}: {{if .Checks}}{{percent .Availability}}{{else}}no data{{end}}"></span>{{end}}</div>
+</div>
+{{end}}
+<h2>Past incidents</h2>
+{{if .Incidents}}
+<ul class="incidents">
+{{range .Incidents}}<li><strong>{{.URL}}</strong> was down from {{time .Start}} {{if .End}}to {{time .End}}{{else}}(ongoing){{end}}, {{.Duration}}</li>
+{{end}}
+</ul>
+{{else}}
+<p>No incidents reported.</p>
+{{end}}
+<footer>Generated on {{time .Generated}}</footer>
+</body>
+</html>
+`
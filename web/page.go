@@ -0,0 +1,136 @@
+package web
+
+// page is the web dashboard, it builds its panels from the "views" event and fills them from the "stats" events
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Websites monitor</title>
+<style>
+  body { background: #1d1f21; color: #c5c8c6; font-family: monospace; margin: 0 20px; }
+  h1 { font-size: 18px; }
+  h2 { color: #8abeb7; font-size: 14px; font-weight: normal; }
+  section { border: 1px solid #373b41; margin-bottom: 16px; padding: 0 12px 12px; }
+  table { border-collapse: collapse; width: 100%; }
+  th { color: #f0c674; text-align: right; }
+  th:first-child, td:first-child, td.state { text-align: left; }
+  td { text-align: right; padding: 2px 8px; }
+  canvas { width: 100%; height: 160px; margin-top: 8px; }
+  .up { color: #b5bd68; } .down { color: #cc6666; } .flapping { color: #f0c674; }
+  #alerts div { padding: 2px 0; white-space: pre-wrap; }
+  .critical { color: #cc6666; } .warning { color: #f0c674; } .info { color: #b5bd68; }
+  #status { color: #969896; }
+</style>
+</head>
+<body>
+<h1>Websites monitor <span id="status"></span></h1>
+<div id="views"></div>
+<section><h2>Alerts</h2><div id="alerts"></div></section>
+<script>
+var colors = ["#81a2be", "#b294bb", "#8abeb7", "#de935f", "#f0c674", "#b5bd68", "#cc6666"];
+
+function el(tag, text, className) {
+  var e = document.createElement(tag);
+  if (text !== undefined) { e.textContent = text; }
+  if (className) { e.className = className; }
+  return e;
+}
+
+function ms(v) { return v.toFixed(2) + "ms"; }
+
+function onViews(views) {
+  var container = document.getElementById("views");
+  container.innerHTML = "";
+  views.forEach(function (view, i) {
+    var section = el("section");
+    section.id = "view-" + i;
+    var tags = Object.keys(view.tags || {}).sort().map(function (key) { return key + "=" + view.tags[key]; });
+    section.appendChild(el("h2", "Statistics for the last " + view.timeFrame + "s (updated every " + view.updateInterval + "s)" + (tags.length ? " [" + tags.join(" ") + "]" : "")));
+    var table = el("table");
+    var header = el("tr");
+    [view.grouped ? "group" : "website", "state", "availability", "avg rt", "max rt", "avg ttfb", "max ttfb", "status codes"].forEach(function (h) {
+      header.appendChild(el("th", h));
+    });
+    table.appendChild(header);
+    section.appendChild(table);
+    section.appendChild(el("canvas"));
+    section.appendChild(el("p", "One moment, we're waiting for statistics...", "loading"));
+    container.appendChild(section);
+  });
+}
+
+function onStats(stats) {
+  var section = document.getElementById("view-" + stats.view);
+  if (!section) { return; }
+  var loading = section.querySelector(".loading");
+  if (loading) { loading.remove(); }
+
+  var table = section.querySelector("table");
+  while (table.rows.length > 1) { table.deleteRow(1); }
+  stats.rows.forEach(function (row) {
+    var tr = el("tr");
+    var codes = Object.keys(row.statusCodes || {}).map(function (code) { return code + ":" + row.statusCodes[code]; });
+    tr.appendChild(el("td", row.name));
+    tr.appendChild(el("td", row.state, "state " + row.state));
+    tr.appendChild(el("td", (100 * row.availability).toFixed(2) + "%"));
+    tr.appendChild(el("td", ms(row.avgResponseTimeMs)));
+    tr.appendChild(el("td", ms(row.maxResponseTimeMs)));
+    tr.appendChild(el("td", ms(row.avgTimeToFirstByteMs)));
+    tr.appendChild(el("td", ms(row.maxTimeToFirstByteMs)));
+    tr.appendChild(el("td", "[" + codes.join(" ") + "]"));
+    table.appendChild(tr);
+  });
+  drawChart(section.querySelector("canvas"), stats.rows);
+}
+
+// drawChart plots the average response time of each website over the timeframe of the view
+function drawChart(canvas, rows) {
+  var width = canvas.width = canvas.clientWidth;
+  var height = canvas.height = canvas.clientHeight;
+  var ctx = canvas.getContext("2d");
+  var max = 0;
+  rows.forEach(function (row) {
+    row.series.forEach(function (p) { max = Math.max(max, p.responseTimeMs); });
+  });
+  if (max === 0) { return; }
+
+  ctx.fillStyle = "#969896";
+  ctx.font = "11px monospace";
+  ctx.fillText(ms(max), 4, 12);
+  rows.forEach(function (row, i) {
+    var n = row.series.length;
+    ctx.strokeStyle = colors[i % colors.length];
+    ctx.fillStyle = ctx.strokeStyle;
+    ctx.fillText(row.name, width - 260, 14 * (i + 1));
+    ctx.beginPath();
+    var started = false;
+    row.series.forEach(function (p, j) {
+      if (p.count === 0) { started = false; return; }
+      var x = n > 1 ? j * (width - 1) / (n - 1) : 0;
+      var y = height - 1 - p.responseTimeMs / max * (height - 20);
+      if (started) { ctx.lineTo(x, y); } else { ctx.moveTo(x, y); started = true; }
+    });
+    ctx.stroke();
+  });
+}
+
+function onAlert(alert) {
+  var message = alert.message;
+  if (alert.suppressedBy) { message = "[suppressed, " + alert.suppressedBy + " is down] " + message; }
+  if (alert.silenced) { message = "[silenced] " + message; }
+  var alerts = document.getElementById("alerts");
+  alerts.insertBefore(el("div", message, alert.severity), alerts.firstChild);
+}
+
+var source = new EventSource("/events");
+source.addEventListener("reset", function () { document.getElementById("alerts").innerHTML = ""; });
+source.addEventListener("views", function (e) { onViews(JSON.parse(e.data)); });
+source.addEventListener("stats", function (e) { onStats(JSON.parse(e.data)); });
+source.addEventListener("alert", function (e) { onAlert(JSON.parse(e.data)); });
+source.addEventListener("failure", function (e) { document.getElementById("status").textContent = JSON.parse(e.data); });
+source.onopen = function () { document.getElementById("status").textContent = ""; };
+source.onerror = function () { document.getElementById("status").textContent = "(disconnected, retrying...)"; };
+</script>
+</body>
+</html>
+`
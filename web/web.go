@@ -0,0 +1,216 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/ayoubed/datadog-home-project/alerting"
+	"github.com/ayoubed/datadog-home-project/dashboard"
+	"github.com/ayoubed/datadog-home-project/monitor"
+	"github.com/ayoubed/datadog-home-project/secret"
+	"github.com/ayoubed/datadog-home-project/statsagent"
+)
+
+// seriesPoints is the number of points of the latency charts
+const seriesPoints = 60
+
+// alertHistorySize is the number of stored alerts sent when a browser connects
+const alertHistorySize = 100
+
+// Config tells where the web dashboard listens, it is disabled when Listen is empty
+type Config struct {
+	Listen string `json:"listen"`
+}
+
+// Enabled tells if the web dashboard should be started
+func (c Config) Enabled() bool {
+	return c.Listen != ""
+}
+
+// server serves the web dashboard, sites and views are called on every update so they follow config changes
+type server struct {
+	ctx   context.Context
+	sites func() []monitor.Website
+	views func() []dashboard.View
+}
+
+// row is the stats of a website or of a group of websites in a view, durations are in milliseconds
+// URL is empty for a group
+type row struct {
+	Name               string         `json:"name"`
+	URL                string         `json:"url"`
+	State              string         `json:"state"`
+	Availability       float64        `json:"availability"`
+	AvgResponseTime    float64        `json:"avgResponseTimeMs"`
+	MaxResponseTime    float64        `json:"maxResponseTimeMs"`
+	AvgTimeToFirstByte float64        `json:"avgTimeToFirstByteMs"`
+	MaxTimeToFirstByte float64        `json:"maxTimeToFirstByteMs"`
+	StatusCodeCount    map[string]int `json:"statusCodes"`
+	Series             []point        `json:"series"`
+}
+
+type point struct {
+	Time         time.Time `json:"time"`
+	ResponseTime float64   `json:"responseTimeMs"`
+	Availability float64   `json:"availability"`
+	Count        int       `json:"count"`
+}
+
+type statsEvent struct {
+	View int       `json:"view"`
+	Time time.Time `json:"time"`
+	Rows []row     `json:"rows"`
+}
+
+// Run serves the web dashboard until the context is done
+func Run(ctx context.Context, config Config, sites func() []monitor.Website, views func() []dashboard.View) error {
+	s := &server{ctx: ctx, sites: sites, views: views}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.page)
+	mux.HandleFunc("/events", s.events)
+
+	httpServer := &http.Server{Addr: config.Listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("web dashboard error: %v", err)
+	}
+	return nil
+}
+
+func (s *server) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, page)
+}
+
+// events streams the views, their stats and the alerts as Server-Sent Events
+func (s *server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	alerts, unsubscribe := alerting.Subscribe(alertHistorySize)
+	defer unsubscribe()
+
+	// browsers reconnecting get the whole history again
+	send(w, flusher, "reset", nil)
+	history, err := alerting.History(alertHistorySize)
+	if err != nil {
+		send(w, flusher, "failure", err.Error())
+		return
+	}
+	for _, alert := range history {
+		send(w, flusher, "alert", alert)
+	}
+
+	var views []dashboard.View
+	var next []time.Time
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		// views are sent again when the config changes
+		if current := s.views(); !reflect.DeepEqual(current, views) {
+			views = current
+			next = make([]time.Time, len(views))
+			send(w, flusher, "views", views)
+		}
+		for i, view := range views {
+			if now.Before(next[i]) {
+				continue
+			}
+			next[i] = now.Add(time.Duration(view.UpdateInterval) * time.Second)
+			event, err := s.stats(i, view, now)
+			if err != nil {
+				send(w, flusher, "failure", err.Error())
+				continue
+			}
+			send(w, flusher, "stats", event)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-r.Context().Done():
+			return
+		case alert, ok := <-alerts:
+			if !ok {
+				return
+			}
+			if !inHistory(history, alert) {
+				send(w, flusher, "alert", alert)
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// inHistory tells if a live alert was already sent with the history, like the alerts published between the subscription and the loading of the history
+func inHistory(history []alerting.Alert, alert alerting.Alert) bool {
+	for i := len(history) - 1; i >= 0 && !history[i].Time.Before(alert.Time.Truncate(time.Millisecond)); i-- {
+		if history[i].Same(alert) {
+			return true
+		}
+	}
+	return false
+}
+
+// stats computes the rows of a view like the terminal dashboard does, filtered by the tags of the view and grouped when it is
+func (s *server) stats(index int, view dashboard.View, origin time.Time) (statsEvent, error) {
+	rows, err := dashboard.Rows(view, s.sites(), origin, seriesPoints)
+	if err != nil {
+		return statsEvent{}, err
+	}
+
+	event := statsEvent{View: index, Time: origin, Rows: make([]row, 0, len(rows))}
+	for _, r := range rows {
+		points := make([]point, 0, len(r.Series))
+		for _, p := range r.Series {
+			points = append(points, point{Time: p.Start, ResponseTime: statsagent.Milliseconds(p.AvgResponseTime), Availability: p.Availability, Count: p.Count})
+		}
+
+		value := r.Stats
+		event.Rows = append(event.Rows, row{
+			Name:               r.Name,
+			URL:                r.URL,
+			State:              r.State,
+			Availability:       value.Availability,
+			AvgResponseTime:    statsagent.Milliseconds(value.AvgResponseTime),
+			MaxResponseTime:    statsagent.Milliseconds(value.MaxResponseTime),
+			AvgTimeToFirstByte: statsagent.Milliseconds(value.AvgTimeToFirstByte),
+			MaxTimeToFirstByte: statsagent.Milliseconds(value.MaxTimeToFirstByte),
+			StatusCodeCount:    value.StatusCodeCount,
+			Series:             points,
+		})
+	}
+	return event, nil
+}
+
+// send writes a Server-Sent Event with a JSON payload
+func send(w http.ResponseWriter, flusher http.Flusher, event string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, secret.Redact(string(data)))
+	flusher.Flush()
+}